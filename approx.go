@@ -0,0 +1,124 @@
+package vat
+
+import (
+	"context"
+)
+
+// ApproxRequest holds the trader details to compare against the ones VIES has on record for a VAT number.
+// All trader details are optional; VIES only reports a match for the details that were given.
+type ApproxRequest struct {
+	// VATNumber is the VAT number to check, including its country code.
+	VATNumber string
+	// RequesterVATNumber is the VAT number, including its country code, of the party making the check.
+	// VIES only returns a consultation number if it is set.
+	RequesterVATNumber string
+
+	TraderName        string
+	TraderCompanyType string
+	TraderStreet      string
+	TraderPostcode    string
+	TraderCity        string
+}
+
+// MatchResult tells whether a trader detail given in an ApproxRequest matches the one on record.
+type MatchResult int
+
+// Possible MatchResult values, as defined by VIES.
+const (
+	MatchUnknown      MatchResult = 0
+	MatchValid        MatchResult = 1
+	MatchInvalid      MatchResult = 2
+	MatchNotProcessed MatchResult = 3
+)
+
+// String returns the name VIES uses for the match result
+func (m MatchResult) String() string {
+	switch m {
+	case MatchValid:
+		return "VALID"
+	case MatchInvalid:
+		return "INVALID"
+	case MatchNotProcessed:
+		return "NOT_PROCESSED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ApproxResult holds the outcome of an approximate check. Name and Address hold the trader details on record,
+// the remaining trader details are only returned by member states that store them separately.
+type ApproxResult struct {
+	ValidationResult
+
+	TraderCompanyType string
+	TraderStreet      string
+	TraderPostcode    string
+	TraderCity        string
+
+	NameMatch        MatchResult
+	CompanyTypeMatch MatchResult
+	StreetMatch      MatchResult
+	PostcodeMatch    MatchResult
+	CityMatch        MatchResult
+}
+
+// approxLookupService is implemented by lookup services that support approximate checks.
+type approxLookupService interface {
+	LookupApproxContext(ctx context.Context, req ApproxRequest) (*ApproxResult, error)
+}
+
+// CheckApprox checks a VAT number with VIES and compares the given trader details against the ones on record.
+// If a requester VAT number is given, the result holds the consultation number VIES assigned to the check.
+func CheckApprox(req ApproxRequest) (*ApproxResult, error) {
+	return defaultClient.CheckApprox(req)
+}
+
+// CheckApproxContext is like CheckApprox, but the context is used for the call to VIES.
+func CheckApproxContext(ctx context.Context, req ApproxRequest) (*ApproxResult, error) {
+	return defaultClient.CheckApproxContext(ctx, req)
+}
+
+// CheckApprox checks a VAT number with VIES and compares the given trader details against the ones on record.
+func (c *Client) CheckApprox(req ApproxRequest) (*ApproxResult, error) {
+	return c.CheckApproxContext(context.Background(), req)
+}
+
+// CheckApproxContext is like CheckApprox, but the context is used for the call to VIES.
+func (c *Client) CheckApproxContext(ctx context.Context, req ApproxRequest) (*ApproxResult, error) {
+	req.VATNumber = normalizeVATNumber(req.VATNumber)
+	req.RequesterVATNumber = normalizeVATNumber(req.RequesterVATNumber)
+
+	if err := ValidateFormat(req.VATNumber); err != nil {
+		return nil, err
+	}
+	if req.RequesterVATNumber != "" {
+		if err := ValidateFormat(req.RequesterVATNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	s := c.getViesApproxService()
+	lookup := func(ctx context.Context) (*ApproxResult, error) {
+		release, err := c.waitRateLimit(ctx, ServiceVIES, req.VATNumber[0:2])
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return s.LookupApproxContext(ctx, req)
+	}
+	if c.retryPolicy == nil {
+		return lookup(ctx)
+	}
+
+	var r *ApproxResult
+	attempts, err := c.retryPolicy.do(ctx, func(ctx context.Context) error {
+		var err error
+		r, err = lookup(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Attempts = attempts
+	return r, nil
+}
@@ -0,0 +1,77 @@
+package vat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const viesApproxResponse = `<env:Envelope xmlns:env="http://schemas.xmlsoap.org/soap/envelope/">
+<env:Body>
+  <ns2:checkVatApproxResponse xmlns:ns2="urn:ec.europa.eu:taxud:vies:services:checkVat:types">
+    <ns2:countryCode>BE</ns2:countryCode>
+    <ns2:vatNumber>0472429986</ns2:vatNumber>
+    <ns2:requestDate>2024-03-06+01:00</ns2:requestDate>
+    <ns2:valid>true</ns2:valid>
+    <ns2:traderName>NV ACME</ns2:traderName>
+    <ns2:traderCompanyType>---</ns2:traderCompanyType>
+    <ns2:traderAddress>Main Street 1 1000 Brussel</ns2:traderAddress>
+    <ns2:traderNameMatch>1</ns2:traderNameMatch>
+    <ns2:traderCityMatch>2</ns2:traderCityMatch>
+    <ns2:requestIdentifier>WAPIAAAAYX1Zq2kT</ns2:requestIdentifier>
+  </ns2:checkVatApproxResponse>
+</env:Body>
+</env:Envelope>`
+
+func TestCheckApprox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for _, want := range []string{
+			"<checkVatApprox ",
+			"<countryCode>BE</countryCode>",
+			"<vatNumber>0472429986</vatNumber>",
+			"<traderName>NV ACME &amp; Co</traderName>",
+			"<traderCity>Gent</traderCity>",
+			"<requesterCountryCode>NL</requesterCountryCode>",
+			"<requesterVatNumber>004495445B01</requesterVatNumber>",
+		} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("Expected request to contain %s, got %s", want, body)
+			}
+		}
+		if strings.Contains(string(body), "traderStreet") {
+			t.Errorf("Expected empty trader details to be left out, got %s", body)
+		}
+		_, _ = io.WriteString(w, viesApproxResponse)
+	}))
+	defer server.Close()
+
+	r, err := NewClient(WithViesURL(server.URL)).CheckApprox(ApproxRequest{
+		VATNumber:          "be0472429986",
+		RequesterVATNumber: "NL004495445B01",
+		TraderName:         "NV ACME & Co",
+		TraderCity:         "Gent",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.Name != "NV ACME" || r.ConsultationNumber != "WAPIAAAAYX1Zq2kT" {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.NameMatch != MatchValid || r.CityMatch != MatchInvalid || r.StreetMatch != MatchUnknown {
+		t.Errorf("Unexpected match results: %v %v %v", r.NameMatch, r.CityMatch, r.StreetMatch)
+	}
+	if r.TraderCompanyType != "" {
+		t.Errorf("Expected empty company type, got %q", r.TraderCompanyType)
+	}
+}
+
+func TestCheckApproxInvalidRequester(t *testing.T) {
+	_, err := CheckApprox(ApproxRequest{VATNumber: "BE0472429986", RequesterVATNumber: "XX1"})
+	if !errors.Is(err, ErrInvalidCountryCode) {
+		t.Errorf("Expected <%v>, got <%v>", ErrInvalidCountryCode, err)
+	}
+}
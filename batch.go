@@ -0,0 +1,129 @@
+package vat
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchOpts are options for ValidateBatch.
+type BatchOpts struct {
+	ValidatorOpts
+
+	// Concurrency is the maximum number of lookups in flight. Defaults to 10.
+	Concurrency int
+	// ServiceConcurrency is the maximum number of lookups in flight per lookup service, e.g. VIES. Zero means
+	// only Concurrency applies.
+	ServiceConcurrency int
+	// MemberStateConcurrency is the maximum number of lookups in flight per country code, to avoid hitting the
+	// per member state limits of VIES. Zero means only Concurrency and ServiceConcurrency apply.
+	MemberStateConcurrency int
+}
+
+// BatchResult is the outcome of validating a single VAT number in a batch.
+type BatchResult struct {
+	// Input is the VAT number as it was passed in
+	Input string
+	// VATNumber is the normalized VAT number that was validated
+	VATNumber string
+	// Result holds the details returned by the lookup service, if the number could be looked up
+	Result *ValidationResult
+	// Err is nil if the VAT number is valid, otherwise it holds the error Validate would have returned
+	Err error
+}
+
+const defaultBatchConcurrency = 10
+
+// ValidateBatch validates many VAT numbers by both format and existence. See Client.ValidateBatch.
+func ValidateBatch(ctx context.Context, vatNumbers []string, opts BatchOpts) []BatchResult {
+	return defaultClient.ValidateBatch(ctx, vatNumbers, opts)
+}
+
+// ValidateBatch validates many VAT numbers by both format and existence. The numbers are normalized and
+// deduplicated, their format is validated locally and the remaining numbers are looked up concurrently within the
+// limits set in opts, and the rate limits of the client. The results are returned in the same order as the input.
+func (c *Client) ValidateBatch(ctx context.Context, vatNumbers []string, opts BatchOpts) []BatchResult {
+	results := make([]BatchResult, len(vatNumbers))
+	indexes := make(map[string][]int, len(vatNumbers))
+	var unique []string
+	for i, input := range vatNumbers {
+		n := normalizeVATNumber(input)
+		results[i] = BatchResult{Input: input, VATNumber: n}
+		if _, ok := indexes[n]; !ok {
+			unique = append(unique, n)
+		}
+		indexes[n] = append(indexes[n], i)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > len(unique) {
+		concurrency = len(unique)
+	}
+	limiters := &rateLimiters{limiters: make(map[string]*rateLimiter)}
+
+	// a fixed number of workers looks up the numbers, so that large batches don't start a goroutine per number
+	lookups := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range lookups {
+				r, err := c.validateInBatch(ctx, limiters, n, opts)
+				for _, i := range indexes[n] {
+					results[i].Result = r
+					results[i].Err = err
+				}
+			}
+		}()
+	}
+
+	for _, n := range unique {
+		if err := ValidateFormat(n, opts.ValidatorOpts); err != nil {
+			for _, i := range indexes[n] {
+				results[i].Err = err
+			}
+			continue
+		}
+		lookups <- n
+	}
+	close(lookups)
+	wg.Wait()
+
+	return results
+}
+
+// validateInBatch looks up a single VAT number once the per service and per member state limits of the batch
+// allow it.
+func (c *Client) validateInBatch(
+	ctx context.Context,
+	limiters *rateLimiters,
+	vatNumber string,
+	opts BatchOpts,
+) (*ValidationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+
+	var batchLimiters []*rateLimiter
+	if opts.MemberStateConcurrency > 0 {
+		limit := RateLimit{MaxConcurrent: opts.MemberStateConcurrency}
+		batchLimiters = append(batchLimiters, limiters.limiter("country:"+vatNumber[0:2], limit))
+	}
+	if opts.ServiceConcurrency > 0 {
+		limit := RateLimit{MaxConcurrent: opts.ServiceConcurrency}
+		service := lookupServiceName(vatNumber, opts.ValidatorOpts)
+		batchLimiters = append(batchLimiters, limiters.limiter("service:"+service, limit))
+	}
+
+	release, err := waitRateLimiters(ctx, batchLimiters)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	r, err := c.LookupExistsContext(ctx, vatNumber, opts.ValidatorOpts)
+	return r, validateResult(r, err)
+}
@@ -0,0 +1,70 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateBatch(t *testing.T) {
+	server := newViesCountingServer("DE")
+	defer server.Close()
+
+	input := []string{
+		"BE0472429986",
+		"be 0472.429.986",
+		"DE136695976",
+		"XX123",
+		"BE0403019360",
+		"BE0403019459",
+		"BE0403019558",
+		"NL123",
+	}
+
+	c := NewClient(WithViesURL(server.URL))
+	results := c.ValidateBatch(context.Background(), input, BatchOpts{Concurrency: 10, MemberStateConcurrency: 2})
+
+	if len(results) != len(input) {
+		t.Fatalf("Expected %d results, got %d", len(input), len(results))
+	}
+
+	var expected = []struct {
+		vatNumber string
+		err       error
+	}{
+		{"BE0472429986", nil},
+		{"BE0472429986", nil},
+		{"DE136695976", ErrVATNumberNotFound},
+		{"XX123", ErrInvalidCountryCode},
+		{"BE0403019360", nil},
+		{"BE0403019459", nil},
+		{"BE0403019558", nil},
+		{"NL123", ErrInvalidVATNumberFormat},
+	}
+	for i, e := range expected {
+		r := results[i]
+		if r.Input != input[i] || r.VATNumber != e.vatNumber || !errors.Is(r.Err, e.err) {
+			t.Errorf("Expected %s <%v> for %q, got %s <%v>", e.vatNumber, e.err, input[i], r.VATNumber, r.Err)
+		}
+	}
+	if results[0].Result == nil || !results[0].Result.Valid {
+		t.Errorf("Expected lookup result for %s, got %+v", input[0], results[0].Result)
+	}
+
+	if server.calls["BE"] != 4 || server.calls["DE"] != 1 {
+		t.Errorf("Expected duplicates and invalid formats not to be looked up, got calls %v", server.calls)
+	}
+	if server.maxInFlight["BE"] > 2 {
+		t.Errorf("Expected at most 2 concurrent lookups for BE, got %d", server.maxInFlight["BE"])
+	}
+}
+
+func TestValidateBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := ValidateBatch(ctx, []string{"BE0472429986"}, BatchOpts{})
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("Expected <%v>, got <%v>", context.Canceled, results[0].Err)
+	}
+}
@@ -0,0 +1,137 @@
+package vat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NOBrregLookupService validates Norwegian VAT numbers with the Central Coordinating Register for Legal Entities
+// (Enhetsregisteret) kept by Brønnøysundregistrene.
+var NOBrregLookupService LookupServiceInterface = &brregService{}
+
+// brregService validates Norwegian VAT numbers with Enhetsregisteret
+type brregService struct {
+	client *Client
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *brregService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for the call to Enhetsregisteret.
+func (s *brregService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details Enhetsregisteret holds for the given VAT number. An organisation that doesn't exist,
+// was deleted or isn't in the VAT register is reported through ValidationResult.Valid rather than an error.
+func (s *brregService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the call to Enhetsregisteret.
+func (s *brregService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	_ ValidatorOpts,
+) (*ValidationResult, error) {
+	n := normalizeVATNumber(vatNumber)
+	if !strings.HasPrefix(n, "NO") || len(n) != 11 || !isDigits(n[2:]) {
+		return nil, ErrInvalidVATNumberFormat
+	}
+
+	c := s.getClient()
+	req, err := http.NewRequestWithContext(ctx, "GET", c.getBrregURL()+"/enheter/"+n[2:], nil)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	result := &ValidationResult{
+		CountryCode: "NO",
+		VATNumber:   n[2:],
+		RequestDate: time.Now(),
+		Service:     ServiceBRREG,
+		Attempts:    1,
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		// Gone is returned for organisations that were removed from the register
+		return result, nil
+	case http.StatusBadRequest:
+		return nil, ErrInvalidVATNumberFormat
+	case http.StatusTooManyRequests:
+		return nil, ErrRateLimited{
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+			Err:        errors.New("Enhetsregisteret rate limit hit"),
+		}
+	default:
+		return nil, ErrServiceUnavailable{
+			Err: fmt.Errorf("unexpected status code from Enhetsregisteret: %d", res.StatusCode),
+		}
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+	var rd brregEntity
+	if err = json.Unmarshal(body, &rd); err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+
+	result.Valid = rd.RegisteredForVAT && rd.DeletedOn == ""
+	result.Name = strings.TrimSpace(rd.Name)
+	address := rd.BusinessAddress
+	if address == nil {
+		address = rd.PostalAddress
+	}
+	if address != nil {
+		lines := append([]string{}, address.Lines...)
+		lines = append(lines, strings.TrimSpace(address.PostalCode+" "+address.City))
+		result.Address = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return result, nil
+}
+
+func (s *brregService) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	return defaultClient
+}
+
+// brregEntity is the part of an entity in Enhetsregisteret that is needed to validate its VAT number
+type brregEntity struct {
+	OrganisationNumber string        `json:"organisasjonsnummer"`
+	Name               string        `json:"navn"`
+	RegisteredForVAT   bool          `json:"registrertIMvaregisteret"`
+	DeletedOn          string        `json:"slettedato"`
+	BusinessAddress    *brregAddress `json:"forretningsadresse"`
+	PostalAddress      *brregAddress `json:"postadresse"`
+}
+
+// brregAddress is an address in Enhetsregisteret
+type brregAddress struct {
+	Lines      []string `json:"adresse"`
+	PostalCode string   `json:"postnummer"`
+	City       string   `json:"poststed"`
+}
+
+const brregServiceURL = "https://data.brreg.no/enhetsregisteret/api"
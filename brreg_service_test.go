@@ -0,0 +1,82 @@
+package vat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const brregEntityResponse = `{
+	"organisasjonsnummer": "995525828",
+	"navn": "ACME AS",
+	"registrertIMvaregisteret": true,
+	"forretningsadresse": {
+		"adresse": ["Karl Johans gate 1"],
+		"postnummer": "0154",
+		"poststed": "OSLO"
+	}
+}`
+
+const brregDeletedResponse = `{
+	"organisasjonsnummer": "988077917",
+	"navn": "GAMLE ACME AS",
+	"registrertIMvaregisteret": true,
+	"slettedato": "2020-01-01"
+}`
+
+func newBrregTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Unexpected Accept header %q", r.Header.Get("Accept"))
+		}
+
+		switch r.URL.Path {
+		case "/enheter/995525828":
+			_, _ = io.WriteString(w, brregEntityResponse)
+		case "/enheter/988077917":
+			_, _ = io.WriteString(w, brregDeletedResponse)
+		case "/enheter/923609016":
+			w.WriteHeader(http.StatusNotFound)
+		case "/enheter/974760673":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+}
+
+func TestBrregService(t *testing.T) {
+	server := newBrregTestServer(t)
+	defer server.Close()
+
+	c := NewClient(WithBrregURL(server.URL))
+
+	r, err := c.Lookup("NO 995 525 828 MVA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.CountryCode != "NO" || r.VATNumber != "995525828" || r.Service != ServiceBRREG {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.Name != "ACME AS" || r.Address != "Karl Johans gate 1\n0154 OSLO" {
+		t.Errorf("Unexpected name and address: %q %q", r.Name, r.Address)
+	}
+
+	// deleted organisations are no longer registered for VAT
+	if err = c.Validate("NO988077917"); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v>, got <%v>", ErrVATNumberNotFound, err)
+	}
+
+	if err = c.Validate("NO923609016MVA"); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v>, got <%v>", ErrVATNumberNotFound, err)
+	}
+
+	err = c.ValidateExists("NO974760673")
+	var rateLimited ErrRateLimited
+	if !errors.As(err, &rateLimited) || rateLimited.RetryAfter.Seconds() != 30 {
+		t.Errorf("Expected <%v>, got <%v>", ErrRateLimited{}, err)
+	}
+}
@@ -0,0 +1,345 @@
+package vat
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cache stores the outcome of lookups so that the same VAT number isn't looked up again for a while.
+// Implement it to store outcomes in e.g. Redis; NewMemoryCache returns an in-memory implementation.
+type Cache interface {
+	// Get returns the entry stored for the key, or false if there is none or it has expired.
+	Get(ctx context.Context, key string) (*CacheEntry, bool)
+	// Set stores the entry for the key for the given time.
+	Set(ctx context.Context, key string, entry *CacheEntry, ttl time.Duration)
+}
+
+// CacheEntry is the outcome of a lookup as stored in a Cache. Either Result or Error is set.
+// It can be encoded as JSON, e.g. to store it in Redis.
+type CacheEntry struct {
+	Result   *ValidationResult
+	Error    *CachedError
+	StoredAt time.Time
+}
+
+// CachedError is the error a lookup failed with in a form that can be encoded, e.g. as JSON.
+// Err rebuilds the typed error, so that it can still be matched with errors.Is and errors.As.
+type CachedError struct {
+	// Kind identifies the type of the error
+	Kind string
+	// Code holds the fault code of a VIES or UK VAT API error, the country of an unavailable member state or the name
+	// of a sentinel error such as ErrVATNumberNotFound
+	Code       string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+	// Cause is the error wrapped by this one, if any
+	Cause *CachedError
+}
+
+// Kinds of cached errors
+const (
+	cachedErrorSentinel               = "sentinel"
+	cachedErrorServiceUnavailable     = "service_unavailable"
+	cachedErrorRateLimited            = "rate_limited"
+	cachedErrorMemberStateUnavailable = "member_state_unavailable"
+	cachedErrorViesFault              = "vies_fault"
+	cachedErrorUKAPI                  = "uk_api"
+	cachedErrorUKToken                = "uk_token"
+	cachedErrorMissingUKToken         = "missing_uk_token"
+	cachedErrorWrapped                = "wrapped"
+	cachedErrorOther                  = "error"
+)
+
+// cachedSentinels are the error values that are cached by name
+var cachedSentinels = map[string]error{
+	"invalid_format":    ErrInvalidVATNumberFormat,
+	"invalid_checksum":  ErrInvalidVATNumberChecksum,
+	"not_found":         ErrVATNumberNotFound,
+	"invalid_country":   ErrInvalidCountryCode,
+	"missing_uk_token":  ErrMissingUKAccessToken,
+	"uk_token_rejected": ErrUKAccessTokenRejected,
+	"context_canceled":  context.Canceled,
+	"context_deadline":  context.DeadlineExceeded,
+}
+
+// newCachedError converts an error to its cached form, or returns nil for a nil error
+func newCachedError(err error) *CachedError {
+	if err == nil {
+		return nil
+	}
+	for name, sentinel := range cachedSentinels {
+		if err == sentinel {
+			return &CachedError{Kind: cachedErrorSentinel, Code: name}
+		}
+	}
+
+	switch e := err.(type) {
+	case ErrServiceUnavailable:
+		return &CachedError{Kind: cachedErrorServiceUnavailable, Cause: newCachedError(e.Err)}
+	case ErrRateLimited:
+		return &CachedError{Kind: cachedErrorRateLimited, RetryAfter: e.RetryAfter, Cause: newCachedError(e.Err)}
+	case ErrMemberStateUnavailable:
+		return &CachedError{Kind: cachedErrorMemberStateUnavailable, Code: e.Country}
+	case *ViesFaultError:
+		return &CachedError{Kind: cachedErrorViesFault, Code: string(e.Code), Message: e.Message}
+	case *UKAPIError:
+		return &CachedError{Kind: cachedErrorUKAPI, StatusCode: e.StatusCode, Code: e.Code, Message: e.Message}
+	case ErrUnableToGenerateUKAccessToken:
+		return &CachedError{Kind: cachedErrorUKToken, Cause: newCachedError(e.Err)}
+	case missingUKAccessTokenError:
+		return &CachedError{Kind: cachedErrorMissingUKToken, Cause: newCachedError(e.Err)}
+	}
+	if cause := errors.Unwrap(err); cause != nil {
+		return &CachedError{Kind: cachedErrorWrapped, Message: err.Error(), Cause: newCachedError(cause)}
+	}
+	return &CachedError{Kind: cachedErrorOther, Message: err.Error()}
+}
+
+// Err returns the typed error the cached error was created from. Errors of unknown types are returned with their
+// message only.
+func (e *CachedError) Err() error {
+	if e == nil {
+		return nil
+	}
+	switch e.Kind {
+	case cachedErrorSentinel:
+		if sentinel, ok := cachedSentinels[e.Code]; ok {
+			return sentinel
+		}
+	case cachedErrorServiceUnavailable:
+		return ErrServiceUnavailable{Err: e.Cause.Err()}
+	case cachedErrorRateLimited:
+		return ErrRateLimited{RetryAfter: e.RetryAfter, Err: e.Cause.Err()}
+	case cachedErrorMemberStateUnavailable:
+		return ErrMemberStateUnavailable{Country: e.Code}
+	case cachedErrorViesFault:
+		return &ViesFaultError{Code: ViesFaultCode(e.Code), Message: e.Message}
+	case cachedErrorUKAPI:
+		return &UKAPIError{StatusCode: e.StatusCode, Code: e.Code, Message: e.Message}
+	case cachedErrorUKToken:
+		return ErrUnableToGenerateUKAccessToken{Err: e.Cause.Err()}
+	case cachedErrorMissingUKToken:
+		return missingUKAccessTokenError{Err: e.Cause.Err()}
+	case cachedErrorWrapped:
+		return &cachedWrappedError{message: e.Message, cause: e.Cause.Err()}
+	}
+	return errors.New(e.Message)
+}
+
+// cachedWrappedError is an error of an unknown type that wrapped another error before it was cached
+type cachedWrappedError struct {
+	message string
+	cause   error
+}
+
+// Error returns the error message
+func (e *cachedWrappedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the wrapped error
+func (e *cachedWrappedError) Unwrap() error {
+	return e.cause
+}
+
+// CachePolicy sets how long the different outcomes of a lookup are cached. A zero TTL disables caching of
+// that outcome.
+type CachePolicy struct {
+	// PositiveTTL applies to VAT numbers that were found to be valid.
+	PositiveTTL time.Duration
+	// NegativeTTL applies to VAT numbers that were found not to exist.
+	NegativeTTL time.Duration
+	// ErrorTTL applies to lookups that failed, e.g. because the service was unavailable.
+	// Cancelled lookups are never cached.
+	ErrorTTL time.Duration
+}
+
+// DefaultCachePolicy caches valid numbers for a day, unknown numbers for an hour and errors for a minute.
+var DefaultCachePolicy = CachePolicy{
+	PositiveTTL: 24 * time.Hour,
+	NegativeTTL: time.Hour,
+	ErrorTTL:    time.Minute,
+}
+
+// WithCache makes the client cache lookup outcomes according to the policy. Results served from the cache have
+// ValidationResult.Cached set.
+func WithCache(cache Cache, policy CachePolicy) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+		c.cachePolicy = policy
+	}
+}
+
+// cachedService wraps a lookup service and caches its outcomes
+type cachedService struct {
+	next   LookupServiceInterface
+	cache  Cache
+	policy CachePolicy
+	prefix string
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *cachedService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for the call to the lookup service and the cache.
+func (s *cachedService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the lookup service holds for the given VAT number.
+func (s *cachedService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the call to the lookup service and the cache.
+func (s *cachedService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ValidatorOpts,
+) (*ValidationResult, error) {
+	key := s.key(vatNumber, opts)
+	if entry, ok := s.cache.Get(ctx, key); ok {
+		if entry.Error != nil {
+			return nil, entry.Error.Err()
+		}
+		r := *entry.Result
+		r.Cached = true
+		r.CachedAt = entry.StoredAt
+		return &r, nil
+	}
+
+	r, err := s.next.LookupContext(ctx, vatNumber, opts)
+
+	entry := &CacheEntry{Result: r, Error: newCachedError(err), StoredAt: time.Now()}
+	var ttl time.Duration
+	switch {
+	case err != nil && !isServiceError(err):
+		ttl = 0
+	case err != nil:
+		ttl = s.policy.ErrorTTL
+	case r.Valid:
+		ttl = s.policy.PositiveTTL
+		entry.Result = copyResult(r)
+	default:
+		ttl = s.policy.NegativeTTL
+		entry.Result = copyResult(r)
+	}
+	if ttl > 0 {
+		s.cache.Set(ctx, key, entry, ttl)
+	}
+	return r, err
+}
+
+// key returns the cache key for the VAT number. Lookups against the UK test API are cached separately, and so are
+// lookups made with a requester VRN, as their consultation numbers are only valid for that requester.
+func (s *cachedService) key(vatNumber string, opts ValidatorOpts) string {
+	key := s.prefix
+	if opts.IsUKTest {
+		key += "test:"
+	}
+	key += vatNumber
+	if opts.UKRequesterVRN != "" {
+		key += "@" + normalizeVATNumber(opts.UKRequesterVRN)
+	}
+	return key
+}
+
+// isServiceError returns whether the error came from the lookup service, rather than from the context or the setup of
+// the caller, like missing credentials or an invalid requester VRN. The cache key doesn't include the setup, so errors
+// caused by it would otherwise be returned to callers with a different setup.
+func isServiceError(err error) bool {
+	var tokenErr ErrUnableToGenerateUKAccessToken
+	var requesterErr ukRequesterVRNError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, ErrMissingUKAccessToken), errors.Is(err, ErrUKAccessTokenRejected):
+		return false
+	case errors.As(err, &tokenErr), errors.As(err, &requesterErr):
+		return false
+	}
+	return true
+}
+
+func copyResult(r *ValidationResult) *ValidationResult {
+	c := *r
+	return &c
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used entries once it is full.
+// It is safe for concurrent use.
+type MemoryCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key       string
+	entry     *CacheEntry
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns a MemoryCache that holds at most size entries.
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored for the key, or false if there is none or it has expired.
+func (c *MemoryCache) Get(_ context.Context, key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*memoryCacheItem)
+	if time.Now().After(item.expiresAt) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return item.entry, true
+}
+
+// Set stores the entry for the key for the given time, evicting the least recently used entry if the cache is full.
+func (c *MemoryCache) Set(_ context.Context, key string, entry *CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if e, ok := c.items[key]; ok {
+		item := e.Value.(*memoryCacheItem)
+		item.entry = entry
+		item.expiresAt = expiresAt
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&memoryCacheItem{key: key, entry: entry, expiresAt: expiresAt})
+	for c.size > 0 && c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired ones that weren't evicted yet.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
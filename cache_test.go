@@ -0,0 +1,184 @@
+package vat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedLookups(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "0472429986"):
+			_, _ = io.WriteString(w, viesValidResponse)
+		case strings.Contains(string(body), "136695976"):
+			_, _ = io.WriteString(w, strings.Replace(viesValidResponse, "true", "false", 1))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, viesFaultResponse, "MS_UNAVAILABLE")
+		}
+	}))
+	defer server.Close()
+
+	cache := NewMemoryCache(10)
+	c := NewClient(WithViesURL(server.URL), WithCache(cache, DefaultCachePolicy))
+
+	r, err := c.Lookup("BE0472429986")
+	if err != nil || r.Cached {
+		t.Fatalf("Expected uncached result, got %+v <%v>", r, err)
+	}
+	r, err = c.Lookup("BE0472429986")
+	if err != nil || !r.Cached || r.CachedAt.IsZero() || r.Name != "NV ACME" {
+		t.Errorf("Expected cached result, got %+v <%v>", r, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = c.Validate("DE136695976"); !errors.Is(err, ErrVATNumberNotFound) {
+			t.Errorf("Expected <%v>, got <%v>", ErrVATNumberNotFound, err)
+		}
+		if err = c.Validate("FR40303265045"); !errors.Is(err, ErrServiceUnavailable{}) {
+			t.Errorf("Expected ErrServiceUnavailable, got <%v>", err)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 calls to VIES, got %d", calls)
+	}
+}
+
+func TestCachePolicyTTLs(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, viesFaultResponse, "MS_UNAVAILABLE")
+	}))
+	defer server.Close()
+
+	c := NewClient(WithViesURL(server.URL), WithCache(NewMemoryCache(10), CachePolicy{PositiveTTL: time.Hour}))
+	for i := 0; i < 2; i++ {
+		_ = c.ValidateExists("BE0472429986")
+	}
+	if calls != 2 {
+		t.Errorf("Expected errors not to be cached without an ErrorTTL, got %d calls", calls)
+	}
+}
+
+func TestCacheSkipsSetupErrors(t *testing.T) {
+	var calls int32
+	server := newUKTokenTestServer(14400, &calls)
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL), WithCache(NewMemoryCache(10), DefaultCachePolicy))
+	if err := c.ValidateExists("GB553557819"); !errors.Is(err, ErrMissingUKAccessToken) {
+		t.Fatalf("Expected <%v>, got <%v>", ErrMissingUKAccessToken, err)
+	}
+	err := c.ValidateExists("GB553557819", ValidatorOpts{UKRequesterVRN: "GB553557818"})
+	if err == nil || !strings.Contains(err.Error(), "requester VRN") {
+		t.Fatalf("Expected an invalid requester VRN, got <%v>", err)
+	}
+
+	// the errors of the first calls are caused by their options, so they aren't served to the next caller
+	if err = c.ValidateExists("GB553557819", ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"}); err != nil {
+		t.Errorf("Expected <nil>, got <%v>", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a token to be generated, got %d calls", calls)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	c.Set(ctx, "a", &CacheEntry{}, time.Hour)
+	c.Set(ctx, "b", &CacheEntry{}, time.Hour)
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Error("Expected a to be cached")
+	}
+	c.Set(ctx, "c", &CacheEntry{}, time.Hour)
+
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("Expected least recently used entry b to be evicted")
+	}
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Error("Expected a to still be cached")
+	}
+
+	c.Set(ctx, "d", &CacheEntry{}, -time.Second)
+	if _, ok := c.Get(ctx, "d"); ok {
+		t.Error("Expected expired entry d not to be returned")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected only c to be left, got %d entries", c.Len())
+	}
+}
+
+func TestCacheEntryJSON(t *testing.T) {
+	var entryTests = []struct {
+		err   error
+		check func(err error) bool
+	}{
+		{nil, func(err error) bool { return err == nil }},
+		{ErrVATNumberNotFound, func(err error) bool { return errors.Is(err, ErrVATNumberNotFound) }},
+		{ErrInvalidVATNumberChecksum, func(err error) bool { return errors.Is(err, ErrInvalidVATNumberFormat) }},
+		{&ViesFaultError{Code: ViesMSMaxConcurrentReq, Message: "MS_MAX_CONCURRENT_REQ"}, func(err error) bool {
+			var fault *ViesFaultError
+			return errors.As(err, &fault) && fault.Code == ViesMSMaxConcurrentReq && IsRetryable(err)
+		}},
+		{ErrServiceUnavailable{Err: &UKAPIError{StatusCode: 503, Code: "SERVER_ERROR"}}, func(err error) bool {
+			var apiErr *UKAPIError
+			return errors.Is(err, ErrServiceUnavailable{}) && errors.As(err, &apiErr) && apiErr.Code == "SERVER_ERROR"
+		}},
+		{ErrRateLimited{RetryAfter: time.Minute, Err: errors.New("slow down")}, func(err error) bool {
+			var rateLimited ErrRateLimited
+			return errors.As(err, &rateLimited) && rateLimited.RetryAfter == time.Minute && err.Error() ==
+				ErrRateLimited{RetryAfter: time.Minute, Err: errors.New("slow down")}.Error()
+		}},
+		{ErrMemberStateUnavailable{Country: "DE"}, func(err error) bool {
+			return errors.Is(err, ErrMemberStateUnavailable{Country: "DE"})
+		}},
+		{missingUKAccessTokenError{Err: ErrUnableToGenerateUKAccessToken{Err: errors.New("no secret")}},
+			func(err error) bool {
+				var genErr ErrUnableToGenerateUKAccessToken
+				return errors.Is(err, ErrMissingUKAccessToken) && errors.As(errors.Unwrap(err), &genErr)
+			}},
+		{fmt.Errorf("vat: invalid UK requester VRN: %w", ErrInvalidVATNumberChecksum), func(err error) bool {
+			return errors.Is(err, ErrInvalidVATNumberChecksum) && err.Error() ==
+				"vat: invalid UK requester VRN: "+ErrInvalidVATNumberChecksum.Error()
+		}},
+	}
+
+	for _, test := range entryTests {
+		entry := &CacheEntry{Error: newCachedError(test.err), StoredAt: time.Now()}
+		if test.err == nil {
+			entry.Result = &ValidationResult{CountryCode: "BE", VATNumber: "0472429986", Valid: true}
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded CacheEntry
+		if err = json.Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("Expected %s to be decoded, got <%v>", b, err)
+		}
+		if err = decoded.Error.Err(); !test.check(err) {
+			t.Errorf("Expected <%v> after decoding %s, got <%v>", test.err, b, err)
+		}
+		if test.err == nil && (decoded.Result == nil || !decoded.Result.Valid) {
+			t.Errorf("Expected the result to be decoded, got %+v", decoded.Result)
+		}
+	}
+}
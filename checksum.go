@@ -0,0 +1,471 @@
+package vat
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checksumAT validates the check digit of an Austrian UID number, "U" followed by 8 digits
+func checksumAT(n string) bool {
+	if !isDigits(n[1:]) {
+		return false
+	}
+	return (6-luhnChecksum(n[1:8])+10)%10 == digit(n[8])
+}
+
+// checksumBE validates the mod 97 check of a Belgian enterprise number
+func checksumBE(n string) bool {
+	return 97-atoi(n[:8])%97 == atoi(n[8:])
+}
+
+// checksumBG validates a Bulgarian VAT number. Legal entities have 9 digits, individuals use their 10 digit
+// personal (EGN) or foreigner (PNF) number and other entities a 10 digit number with its own check digit.
+func checksumBG(n string) bool {
+	if len(n) == 9 {
+		sum := 0
+		for i := 0; i < 8; i++ {
+			sum += (i + 1) * digit(n[i])
+		}
+		if sum%11 == 10 {
+			sum = 0
+			for i := 0; i < 8; i++ {
+				sum += (i + 3) * digit(n[i])
+			}
+		}
+		return sum%11%10 == digit(n[8])
+	}
+
+	check := digit(n[9])
+	if weightedSum(n, 2, 4, 8, 5, 10, 9, 7, 3, 6)%11%10 == check && validEGNDate(n) {
+		return true
+	}
+	if weightedSum(n, 21, 19, 17, 13, 11, 9, 7, 3, 1)%10 == check {
+		return true
+	}
+	other := 11 - weightedSum(n, 4, 3, 2, 7, 6, 5, 4, 3, 2)%11
+	return other != 10 && other%11 == check
+}
+
+// validEGNDate returns whether a Bulgarian personal number starts with a valid date of birth. 20 is added to the
+// month for people born in the 1800s and 40 for people born from 2000.
+func validEGNDate(n string) bool {
+	year, month, day := 1900+atoi(n[0:2]), atoi(n[2:4]), atoi(n[4:6])
+	switch {
+	case month > 40:
+		year, month = year+100, month-40
+	case month > 20:
+		year, month = year-100, month-20
+	}
+	return validDate(year, month, day)
+}
+
+// checksumCH validates the mod 11 check digit of a Swiss UID, "E" followed by 9 digits
+func checksumCH(n string) bool {
+	check := 11 - weightedSum(n[1:], 5, 4, 3, 2, 7, 6, 5, 4)%11
+	return check != 10 && check%11 == digit(n[9])
+}
+
+// checksumCY validates the check letter of a Cypriot VAT number
+func checksumCY(n string) bool {
+	if strings.HasPrefix(n, "12") {
+		return false
+	}
+	odd := [10]int{1, 0, 5, 7, 9, 13, 15, 17, 19, 21}
+	sum := 0
+	for i := 0; i < 8; i++ {
+		if i%2 == 0 {
+			sum += odd[digit(n[i])]
+		} else {
+			sum += digit(n[i])
+		}
+	}
+	return n[8] == byte('A'+sum%26)
+}
+
+// checksumCZ validates a Czech DIČ. Legal entities have 8 digits, individuals use their 9 or 10 digit birth
+// number and 9 digit numbers starting with 6 are special cases with their own check digit.
+func checksumCZ(n string) bool {
+	switch {
+	case len(n) == 8:
+		if n[0] == '9' {
+			return false
+		}
+		check := (11 - weightedSum(n, 8, 7, 6, 5, 4, 3, 2)%11) % 11
+		if check == 0 {
+			check = 1
+		}
+		return check%10 == digit(n[7])
+	case len(n) == 9 && n[0] == '6':
+		check := weightedSum(n[1:], 8, 7, 6, 5, 4, 3, 2) % 11
+		return 9-(11-check)%10 == digit(n[8])
+	default:
+		return validBirthNumberCZ(n)
+	}
+}
+
+// validBirthNumberCZ validates a Czech or Slovak birth number. Numbers issued before 1954 have 9 digits and no
+// check digit, later ones have 10 digits and are divisible by 11, with a remainder of 10 allowed before 1985.
+// 50 is added to the month for women and 20 is added when a day ran out of serial numbers.
+func validBirthNumberCZ(n string) bool {
+	if len(n) != 9 && len(n) != 10 {
+		return false
+	}
+	year, month, day := 1900+atoi(n[0:2]), atoi(n[2:4]), atoi(n[4:6])
+	if month > 50 {
+		month -= 50
+	}
+	if month > 20 {
+		month -= 20
+	}
+	if len(n) == 9 {
+		if year >= 1980 {
+			year -= 100
+		}
+		return year <= 1953 && validDate(year, month, day)
+	}
+
+	if year < 1954 {
+		year += 100
+	}
+	if !validDate(year, month, day) {
+		return false
+	}
+	check := atoi(n[:9]) % 11
+	if year < 1985 {
+		check %= 10
+	}
+	return check == digit(n[9])
+}
+
+// checksumDE validates the ISO 7064 MOD 11,10 check digit of a German USt-IdNr.
+func checksumDE(n string) bool {
+	return mod11x10(n)
+}
+
+// checksumDK validates the mod 11 check of a Danish CVR number
+func checksumDK(n string) bool {
+	return n[0] != '0' && weightedSum(n, 2, 7, 6, 5, 4, 3, 2, 1)%11 == 0
+}
+
+// checksumEE validates the check digit of an Estonian KMKR number
+func checksumEE(n string) bool {
+	return strings.HasPrefix(n, "10") && (10-weightedSum(n, 3, 7, 1, 3, 7, 1, 3, 7)%10)%10 == digit(n[8])
+}
+
+// checksumEL validates the check digit of a Greek VAT number
+func checksumEL(n string) bool {
+	sum := 0
+	for i := 0; i < 8; i++ {
+		sum = sum*2 + digit(n[i])
+	}
+	return sum*2%11%10 == digit(n[8])
+}
+
+// checksumES validates a Spanish NIF. Individuals use their DNI or, for foreigners, their NIE with a control
+// letter, while legal entities use a CIF with a control digit or letter.
+func checksumES(n string) bool {
+	if len(n) != 9 || !isDigits(n[1:8]) {
+		return false
+	}
+	switch {
+	case isDigits(n[:1]):
+		return n[8] == controlLetterES(n[:8])
+	case strings.IndexByte("KLM", n[0]) >= 0:
+		return n[8] == controlLetterES(n[1:8])
+	case strings.IndexByte("XYZ", n[0]) >= 0:
+		return n[8] == controlLetterES(string('0'+n[0]-'X')+n[1:8])
+	case strings.IndexByte("ABCDEFGHJNPQRSUVW", n[0]) >= 0:
+		check := (10 - luhnChecksum(n[1:8]+"0")) % 10
+		return n[8] == byte('0'+check) || n[8] == "JABCDEFGHI"[check]
+	default:
+		return false
+	}
+}
+
+// controlLetterES returns the control letter of a DNI
+func controlLetterES(digits string) byte {
+	return "TRWAGMYFPDXBNJZSQVHLCKE"[atoi(digits)%23]
+}
+
+// checksumFI validates the mod 11 check digit of a Finnish Y-tunnus
+func checksumFI(n string) bool {
+	check := 11 - weightedSum(n, 7, 9, 10, 5, 8, 4, 2)%11
+	return check != 10 && check%11 == digit(n[7])
+}
+
+// checksumFR validates the Luhn check digit of the SIREN of a French VAT number and the 2 character key against it
+func checksumFR(n string) bool {
+	siren := n[2:]
+	// Monaco numbers use SIRENs starting with 000 that don't have a Luhn check digit
+	if !strings.HasPrefix(siren, "000") && luhnChecksum(siren) != 0 {
+		return false
+	}
+	if isDigits(n[:2]) {
+		return atoi(n[:2]) == atoi(siren+"12")%97
+	}
+
+	// newer numbers use a key made of letters and digits
+	const alphabet = "0123456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	first, second := strings.IndexByte(alphabet, n[0]), strings.IndexByte(alphabet, n[1])
+	if first < 0 || second < 0 {
+		return false
+	}
+	var check int
+	if first < 10 {
+		check = first*24 + second - 10
+	} else {
+		check = first*34 + second - 100
+	}
+	return (atoi(siren)+1+check/11)%11 == check%11
+}
+
+// checksumHR validates the ISO 7064 MOD 11,10 check digit of a Croatian OIB
+func checksumHR(n string) bool {
+	return mod11x10(n)
+}
+
+// checksumHU validates the check digit of a Hungarian VAT number
+func checksumHU(n string) bool {
+	return weightedSum(n, 9, 7, 3, 1, 9, 7, 3, 1)%10 == 0
+}
+
+// checksumIE validates the check letter of an Irish VAT number in both the old and new formats
+func checksumIE(n string) bool {
+	if len(n) != 8 && len(n) != 9 {
+		return false
+	}
+	if isDigits(n[:1]) && (n[1] == '+' || n[1] == '*' || (n[1] >= 'A' && n[1] <= 'Z')) && isDigits(n[2:7]) {
+		// old format: the second character is a letter, + or *, followed by 5 digits and the check letter
+		return n[7:] == checkLetterIE("0"+n[2:7]+n[:1], "")
+	}
+	if !isDigits(n[:7]) {
+		return false
+	}
+	return n[7:8] == checkLetterIE(n[:7], n[8:])
+}
+
+// checkLetterIE calculates the check letter of 7 digits and an optional second letter
+func checkLetterIE(digits, extra string) string {
+	const alphabet = "WABCDEFGHIJKLMNOPQRSTUV"
+	sum := 0
+	for i := 0; i < 7; i++ {
+		sum += (8 - i) * digit(digits[i])
+	}
+	if extra != "" {
+		sum += 9 * strings.IndexByte(alphabet, extra[0])
+	}
+	return string(alphabet[sum%23])
+}
+
+// checksumIT validates a partita IVA, which has a Luhn check digit and contains the code of the issuing office
+func checksumIT(n string) bool {
+	if atoi(n[:7]) == 0 {
+		return false
+	}
+	office := atoi(n[7:10])
+	if (office < 1 || office > 100) && office != 120 && office != 121 && office != 888 && office != 999 {
+		return false
+	}
+	return luhnChecksum(n) == 0
+}
+
+// checksumLU validates the mod 89 check of a Luxembourg VAT number
+func checksumLU(n string) bool {
+	return atoi(n[:6])%89 == atoi(n[6:])
+}
+
+// checksumLT validates a Lithuanian VAT number. Legal entities have 9 digits and temporary taxpayers 12, with a
+// 1 before the check digit in both cases.
+func checksumLT(n string) bool {
+	if n[len(n)-2] != '1' {
+		return false
+	}
+	body := n[:len(n)-1]
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		sum += (1 + i%9) * digit(body[i])
+	}
+	if sum%11 == 10 {
+		sum = 0
+		for i := 0; i < len(body); i++ {
+			sum += (1 + (i+2)%9) * digit(body[i])
+		}
+	}
+	return sum%11%10 == digit(n[len(n)-1])
+}
+
+// checksumLV validates a Latvian PVN number. Legal entities have numbers starting with a digit above 3,
+// individuals use their personal code which starts with their date of birth. Personal codes issued since 2017 start
+// with 32 instead and have no check digit.
+func checksumLV(n string) bool {
+	if n[0] > '3' {
+		return weightedSum(n, 9, 1, 4, 8, 3, 10, 2, 5, 7, 6, 1)%11 == 3
+	}
+	if strings.HasPrefix(n, "32") {
+		return true
+	}
+	// the 7th digit is the century of birth, starting at 0 for the 1800s
+	year := 1800 + 100*digit(n[6]) + atoi(n[4:6])
+	if !validDate(year, atoi(n[2:4]), atoi(n[0:2])) {
+		return false
+	}
+	return (1+weightedSum(n, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9))%11%10 == digit(n[10])
+}
+
+// checksumMT validates the mod 37 check of a Maltese VAT number
+func checksumMT(n string) bool {
+	return n[0] != '0' && weightedSum(n, 3, 4, 6, 7, 8, 9, 10, 1)%37 == 0
+}
+
+// checksumNL validates a Dutch VAT number. Companies use the mod 11 check of the RSIN, sole traders have had
+// numbers that are validated with ISO 7064 MOD 97-10 over the whole number since 2020.
+func checksumNL(n string) bool {
+	if atoi(n[10:]) == 0 || atoi(n[:9]) == 0 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 8; i++ {
+		sum += (9 - i) * digit(n[i])
+	}
+	if (sum-digit(n[8]))%11 == 0 {
+		return true
+	}
+	return mod97("NL"+n) == 1
+}
+
+// checksumNO validates the mod 11 check digit of a Norwegian organisation number
+func checksumNO(n string) bool {
+	check := 11 - weightedSum(n, 3, 2, 7, 6, 5, 4, 3, 2)%11
+	return check != 10 && check%11 == digit(n[8])
+}
+
+// checksumPL validates the mod 11 check digit of a Polish NIP
+func checksumPL(n string) bool {
+	return weightedSum(n, 6, 5, 7, 2, 3, 4, 5, 6, 7, -1)%11 == 0
+}
+
+// checksumPT validates the mod 11 check digit of a Portuguese NIF
+func checksumPT(n string) bool {
+	check := 11 - weightedSum(n, 9, 8, 7, 6, 5, 4, 3, 2)%11
+	if check > 9 {
+		check = 0
+	}
+	return check == digit(n[8])
+}
+
+// checksumRO validates the check digit of a Romanian CIF, which has 2 up to 10 digits. The weights are aligned
+// to the right, so the check is the same as for the number padded with leading zeros.
+func checksumRO(n string) bool {
+	weights := []int{7, 5, 3, 2, 1, 7, 5, 3, 2}
+	body := n[:len(n)-1]
+	return weightedSum(body, weights[len(weights)-len(body):]...)*10%11%10 == digit(n[len(n)-1])
+}
+
+// checksumSE validates a Swedish VAT number, which is the organisation number with a Luhn check digit followed by 01
+func checksumSE(n string) bool {
+	return strings.HasSuffix(n, "01") && luhnChecksum(n[:10]) == 0
+}
+
+// checksumSI validates the mod 11 check digit of a Slovenian VAT number
+func checksumSI(n string) bool {
+	if n[0] == '0' {
+		return false
+	}
+	check := 11 - weightedSum(n, 8, 7, 6, 5, 4, 3, 2)%11
+	if check == 10 {
+		check = 0
+	}
+	return check == digit(n[7])
+}
+
+// checksumSK validates a Slovak IČ DPH, which is divisible by 11. Individuals may also use their birth number.
+func checksumSK(n string) bool {
+	if validBirthNumberCZ(n) {
+		return true
+	}
+	return n[0] != '0' && strings.IndexByte("234789", n[2]) >= 0 && atoi(n)%11 == 0
+}
+
+// mod11x10 validates a number with an ISO 7064 MOD 11,10 check digit
+func mod11x10(n string) bool {
+	check := 5
+	for i := 0; i < len(n); i++ {
+		if check == 0 {
+			check = 10
+		}
+		check = (check*2%11 + digit(n[i])) % 10
+	}
+	return check == 1
+}
+
+// mod97 returns the ISO 7064 MOD 97-10 remainder of a number, letters count as 10 for A up to 35 for Z
+func mod97(n string) int {
+	r := 0
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		switch {
+		case c >= '0' && c <= '9':
+			r = (r*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			r = (r*100 + int(c-'A') + 10) % 97
+		default:
+			return -1
+		}
+	}
+	return r
+}
+
+// luhnChecksum returns the Luhn checksum of a number, which is 0 if its last digit is a valid check digit
+func luhnChecksum(n string) int {
+	sum := 0
+	for i := 0; i < len(n); i++ {
+		d := digit(n[len(n)-1-i])
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum % 10
+}
+
+// weightedSum returns the sum of the leading digits of a number multiplied by the given weights
+func weightedSum(n string, weights ...int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += w * digit(n[i])
+	}
+	return sum
+}
+
+// validDate returns whether the year, month and day make up an existing date
+func validDate(year, month, day int) bool {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return t.Year() == year && int(t.Month()) == month && t.Day() == day
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func digit(c byte) int {
+	return int(c - '0')
+}
+
+// atoi converts digits to an int, returning -1 if they aren't all digits
+func atoi(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil || !isDigits(s) {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,346 @@
+package vat
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Client validates VAT numbers and fetches VAT rates using its own HTTP client and service endpoints.
+// Multiple clients with different configurations can be used side by side.
+// The package-level functions use a default client that is configured through the package-level variables.
+type Client struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	userAgent  string
+
+	viesURL     string
+	viesRESTURL string
+	viesBackend ViesBackend
+	ukURL       string
+	ukTestURL   string
+	uidURL      string
+	brregURL    string
+	ratesURL    string
+
+	viesLookupService LookupServiceInterface
+	viesApproxService approxLookupService
+	ukLookupService   LookupServiceInterface
+	chLookupService   LookupServiceInterface
+	noLookupService   LookupServiceInterface
+	viesStatusPoller  atomic.Pointer[ViesStatusPoller]
+	ukTokenSource     *UKTokenSource
+	retryPolicy       *RetryPolicy
+	cache             Cache
+	rateLimiters      *rateLimiters
+	cachePolicy       CachePolicy
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// NewClient returns a Client configured with the given options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.viesLookupService == nil {
+		switch c.viesBackend {
+		case ViesBackendREST:
+			c.viesLookupService = &viesRESTService{client: c}
+		default:
+			c.viesLookupService = &viesService{client: c}
+		}
+	}
+	if c.ukLookupService == nil {
+		c.ukLookupService = &ukVATService{client: c}
+	}
+	if c.chLookupService == nil {
+		c.chLookupService = &uidService{client: c}
+	}
+	if c.noLookupService == nil {
+		c.noLookupService = &brregService{client: c}
+	}
+	if s, ok := c.viesLookupService.(approxLookupService); ok {
+		c.viesApproxService = s
+	}
+
+	c.viesLookupService = c.wrapLookupService(c.viesLookupService, ServiceVIES, "vies:")
+	c.ukLookupService = c.wrapLookupService(c.ukLookupService, ServiceHMRC, "uk:")
+	c.chLookupService = c.wrapLookupService(c.chLookupService, ServiceUID, "ch:")
+	c.noLookupService = c.wrapLookupService(c.noLookupService, ServiceBRREG, "no:")
+	return c
+}
+
+// wrapLookupService adds the client's rate limiting, retry and caching behaviour to a lookup service.
+// The service name selects the rate limits, the cache key prefix keeps the outcomes of different services apart in a
+// shared cache.
+func (c *Client) wrapLookupService(
+	s LookupServiceInterface,
+	service string,
+	cacheKeyPrefix string,
+) LookupServiceInterface {
+	if c.rateLimiters != nil {
+		s = &rateLimitedService{next: s, client: c, service: service}
+	}
+	if c.retryPolicy != nil {
+		s = &retryService{next: s, policy: *c.retryPolicy}
+	}
+	if c.cache != nil {
+		s = &cachedService{next: s, cache: c.cache, policy: c.cachePolicy, prefix: cacheKeyPrefix}
+	}
+	return s
+}
+
+// defaultClient is used by the package-level functions. Its lookup services fall back to
+// ViesLookupService and UKVATLookupService, and its timeout to the one set with SetServiceTimeout.
+var defaultClient = &Client{}
+
+// WithHTTPClient sets the HTTP client used for all calls to external services.
+// The client is used as is, so WithTransport and WithTimeout have no effect when it is set.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the RoundTripper used for all calls to external services, e.g. to route them through a proxy.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout for calls to external services. Defaults to the timeout set with SetServiceTimeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent to external services.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithViesURL sets the URL of the VIES SOAP service.
+func WithViesURL(url string) ClientOption {
+	return func(c *Client) {
+		c.viesURL = url
+	}
+}
+
+// WithViesRESTURL sets the base URL of the VIES REST API, e.g. "https://ec.europa.eu/taxation_customs/vies/rest-api".
+func WithViesRESTURL(url string) ClientOption {
+	return func(c *Client) {
+		c.viesRESTURL = url
+	}
+}
+
+// ViesBackend selects which VIES API is used to look up EU VAT numbers.
+type ViesBackend int
+
+// Available VIES backends
+const (
+	ViesBackendSOAP ViesBackend = iota
+	ViesBackendREST
+)
+
+// WithViesBackend selects the VIES API used to look up EU VAT numbers. Defaults to ViesBackendSOAP.
+// Both backends return the same results and errors.
+func WithViesBackend(backend ViesBackend) ClientOption {
+	return func(c *Client) {
+		c.viesBackend = backend
+	}
+}
+
+// WithUKURL sets the base URL of the UK VAT API, e.g. "https://api.service.hmrc.gov.uk".
+func WithUKURL(url string) ClientOption {
+	return func(c *Client) {
+		c.ukURL = url
+	}
+}
+
+// WithUKTestURL sets the base URL of the UK VAT API used when ValidatorOpts.IsUKTest is set.
+func WithUKTestURL(url string) ClientOption {
+	return func(c *Client) {
+		c.ukTestURL = url
+	}
+}
+
+// WithUIDURL sets the URL of the public services of the Swiss UID register.
+func WithUIDURL(url string) ClientOption {
+	return func(c *Client) {
+		c.uidURL = url
+	}
+}
+
+// WithBrregURL sets the base URL of the Enhetsregisteret API used to look up Norwegian VAT numbers.
+func WithBrregURL(url string) ClientOption {
+	return func(c *Client) {
+		c.brregURL = url
+	}
+}
+
+// WithRatesURL sets the URL the VAT rates are downloaded from.
+// The document must have the same structure as the one published by ibericode/vat-rates.
+func WithRatesURL(url string) ClientOption {
+	return func(c *Client) {
+		c.ratesURL = url
+	}
+}
+
+// WithViesLookupService replaces the service used to look up EU VAT numbers.
+func WithViesLookupService(s LookupServiceInterface) ClientOption {
+	return func(c *Client) {
+		c.viesLookupService = s
+	}
+}
+
+// WithCHLookupService replaces the service used to look up Swiss VAT numbers.
+func WithCHLookupService(s LookupServiceInterface) ClientOption {
+	return func(c *Client) {
+		c.chLookupService = s
+	}
+}
+
+// WithNOLookupService replaces the service used to look up Norwegian VAT numbers.
+func WithNOLookupService(s LookupServiceInterface) ClientOption {
+	return func(c *Client) {
+		c.noLookupService = s
+	}
+}
+
+// WithUKTokenSource sets the token source that provides access tokens for the UK VAT API to lookups that aren't
+// given a valid ValidatorOpts.UKAccessToken.
+func WithUKTokenSource(ts *UKTokenSource) ClientOption {
+	return func(c *Client) {
+		c.ukTokenSource = ts
+	}
+}
+
+// WithUKLookupService replaces the service used to look up UK VAT numbers.
+func WithUKLookupService(s LookupServiceInterface) ClientOption {
+	return func(c *Client) {
+		c.ukLookupService = s
+	}
+}
+
+// WithViesStatusPoller makes lookups for member states the poller reports as unavailable
+// fail fast with ErrMemberStateUnavailable.
+func WithViesStatusPoller(p *ViesStatusPoller) ClientOption {
+	return func(c *Client) {
+		c.viesStatusPoller.Store(p)
+	}
+}
+
+// do sends an HTTP request to an external service.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return c.getHTTPClient().Do(req)
+}
+
+func (c *Client) getHTTPClient() *http.Client {
+	if c.httpClient != nil {
+		return c.httpClient
+	}
+
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = serviceTimeout
+	}
+	return &http.Client{
+		Transport: c.transport,
+		Timeout:   timeout,
+	}
+}
+
+func (c *Client) getViesLookupService() LookupServiceInterface {
+	if c.viesLookupService != nil {
+		return c.viesLookupService
+	}
+	return ViesLookupService
+}
+
+func (c *Client) getViesApproxService() approxLookupService {
+	if c.viesApproxService != nil {
+		return c.viesApproxService
+	}
+	if s, ok := c.getViesLookupService().(approxLookupService); ok {
+		return s
+	}
+	return &viesService{client: c}
+}
+
+func (c *Client) getUKLookupService() LookupServiceInterface {
+	if c.ukLookupService != nil {
+		return c.ukLookupService
+	}
+	return UKVATLookupService
+}
+
+func (c *Client) getCHLookupService() LookupServiceInterface {
+	if c.chLookupService != nil {
+		return c.chLookupService
+	}
+	return CHUIDLookupService
+}
+
+func (c *Client) getNOLookupService() LookupServiceInterface {
+	if c.noLookupService != nil {
+		return c.noLookupService
+	}
+	return NOBrregLookupService
+}
+
+func (c *Client) getViesURL() string {
+	if c.viesURL != "" {
+		return c.viesURL
+	}
+	return viesServiceURL
+}
+
+func (c *Client) getViesRESTURL() string {
+	if c.viesRESTURL != "" {
+		return c.viesRESTURL
+	}
+	return viesRESTServiceURL
+}
+
+func (c *Client) getUKURL(isTest bool) string {
+	if isTest && c.ukTestURL != "" {
+		return c.ukTestURL
+	}
+	if !isTest && c.ukURL != "" {
+		return c.ukURL
+	}
+	return ukVatServiceURL(isTest)
+}
+
+func (c *Client) getUIDURL() string {
+	if c.uidURL != "" {
+		return c.uidURL
+	}
+	return uidServiceURL
+}
+
+func (c *Client) getBrregURL() string {
+	if c.brregURL != "" {
+		return c.brregURL
+	}
+	return brregServiceURL
+}
+
+func (c *Client) getRatesURL() string {
+	if c.ratesURL != "" {
+		return c.ratesURL
+	}
+	return ratesURL
+}
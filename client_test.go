@@ -0,0 +1,215 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const viesValidResponse = `<env:Envelope xmlns:env="http://schemas.xmlsoap.org/soap/envelope/">
+<env:Header/>
+<env:Body>
+  <ns2:checkVatResponse xmlns:ns2="urn:ec.europa.eu:taxud:vies:services:checkVat:types">
+    <ns2:countryCode>BE</ns2:countryCode>
+    <ns2:vatNumber>0472429986</ns2:vatNumber>
+    <ns2:requestDate>2024-03-06+01:00</ns2:requestDate>
+    <ns2:valid>true</ns2:valid>
+    <ns2:name>NV ACME</ns2:name>
+    <ns2:address>Main Street 1
+1000 Brussel</ns2:address>
+  </ns2:checkVatResponse>
+</env:Body>
+</env:Envelope>`
+
+// viesCountingServer is a fake VIES SOAP service that counts the calls it gets, and how many are in flight at once,
+// per country code. Numbers of the countries in invalid are reported as invalid.
+type viesCountingServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	calls       map[string]int
+	inFlight    map[string]int
+	maxInFlight map[string]int
+}
+
+func newViesCountingServer(invalid ...string) *viesCountingServer {
+	countryCode := regexp.MustCompile(`<countryCode>([A-Z]{2})</countryCode>`)
+	invalidResponse := strings.Replace(viesValidResponse, "<ns2:valid>true", "<ns2:valid>false", 1)
+
+	s := &viesCountingServer{calls: map[string]int{}, inFlight: map[string]int{}, maxInFlight: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		cc := countryCode.FindStringSubmatch(string(body))[1]
+
+		s.mu.Lock()
+		s.calls[cc]++
+		s.inFlight[cc]++
+		if s.inFlight[cc] > s.maxInFlight[cc] {
+			s.maxInFlight[cc] = s.inFlight[cc]
+		}
+		s.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		s.mu.Lock()
+		s.inFlight[cc]--
+		s.mu.Unlock()
+
+		for _, i := range invalid {
+			if cc == i {
+				_, _ = io.WriteString(w, invalidResponse)
+				return
+			}
+		}
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	return s
+}
+
+func TestClientVies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "<vatNumber>0472429986</vatNumber>") {
+			t.Errorf("Unexpected VIES request: %s", body)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "vat-test" {
+			t.Errorf("Expected User-Agent vat-test, got %q", ua)
+		}
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	defer server.Close()
+
+	c := NewClient(WithViesURL(server.URL), WithUserAgent("vat-test"))
+	r, err := c.Lookup("BE0472429986")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.Name != "NV ACME" || r.Address != "Main Street 1\n1000 Brussel" || r.Service != ServiceVIES {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.RequestDate.Format("2006-01-02") != "2024-03-06" {
+		t.Errorf("Expected request date 2024-03-06, got %v", r.RequestDate)
+	}
+}
+
+func TestClientUK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			_, _ = io.WriteString(w, `{"access_token":"abc","expires_in":14400}`)
+		case "/organisations/vat/check-vat-number/lookup/553557819":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL))
+	opts := ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"}
+
+	if err := c.Validate("GB553557819", opts); err != nil {
+		t.Errorf("Expected <nil> for GB553557819, got <%v>", err)
+	}
+	if err := c.Validate("GB333289454", opts); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v> for GB333289454, got <%v>", ErrVATNumberNotFound, err)
+	}
+}
+
+const ukTestLookupResponse = `{
+	"target": {
+		"name": "ACME Ltd",
+		"vatNumber": "553557819",
+		"address": {
+			"line1": "1 High Street",
+			"line2": "London",
+			"postcode": "SW1A 1AA",
+			"countryCode": "GB"
+		}
+	},
+	"processingDate": "2024-03-06T10:15:00+00:00"%s
+}`
+
+func TestClientUKLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/organisations/vat/check-vat-number/lookup/553557819":
+			_, _ = io.WriteString(w, strings.Replace(ukTestLookupResponse, "%s", "", 1))
+		case "/organisations/vat/check-vat-number/lookup/553557819/999999973":
+			_, _ = io.WriteString(w, strings.Replace(ukTestLookupResponse, "%s",
+				`, "requester": "999999973", "consultationNumber": "ABC-DEF-GHI"`, 1))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL))
+	opts := ValidatorOpts{UKAccessToken: &UKAccessToken{Token: "abc", ExpiresAt: time.Now().Add(time.Hour)}}
+
+	r, err := c.Lookup("GB553557819", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.Name != "ACME Ltd" || r.Address != "1 High Street\nLondon\nSW1A 1AA" || r.ConsultationNumber != "" {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.RequestDate.Format(time.RFC3339) != "2024-03-06T10:15:00Z" {
+		t.Errorf("Expected the processing date as request date, got %v", r.RequestDate)
+	}
+
+	// the consultation number is only returned for lookups with a requester VRN
+	opts.UKRequesterVRN = "GB 999 9999 73"
+	r, err = c.Lookup("GB553557819", opts)
+	if err != nil || !r.Valid || r.ConsultationNumber != "ABC-DEF-GHI" {
+		t.Errorf("Expected a consultation number, got %+v <%v>", r, err)
+	}
+
+	opts.UKRequesterVRN = "999999974"
+	if _, err = c.Lookup("GB553557819", opts); !errors.Is(err, ErrInvalidVATNumberChecksum) {
+		t.Errorf("Expected <%v> for an invalid requester VRN, got <%v>", ErrInvalidVATNumberChecksum, err)
+	}
+}
+
+func TestClientRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"items":{"NL":[{"effective_from":"2012-10-01","rates":{"standard":21}}]}}`)
+	}))
+	defer server.Close()
+
+	rates, err := NewClient(WithRatesURL(server.URL)).FetchRates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 1 || rates[0].CountryCode != "NL" {
+		t.Fatalf("Unexpected rates: %+v", rates)
+	}
+	if r, _ := rates[0].GetRate("standard"); r != 21 {
+		t.Errorf("Standard VAT rate for NL is supposed to be 21. Got %.2f", r)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	defer server.Close()
+
+	c := NewClient(WithViesURL(server.URL), WithTimeout(10*time.Millisecond))
+	err := c.ValidateExistsContext(context.Background(), "BE0472429986")
+	if !errors.As(err, &ErrServiceUnavailable{}) {
+		t.Errorf("Expected ErrServiceUnavailable, got <%v>", err)
+	}
+}
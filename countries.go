@@ -0,0 +1,143 @@
+package vat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// CountryRule describes how the VAT numbers of a country are validated, looked up and displayed.
+// Register one with RegisterCountry to support a country or to change how one is handled.
+type CountryRule struct {
+	// CountryCode is the 2 letter prefix of the country's VAT numbers, e.g. "EL" for Greece.
+	CountryCode string
+	// Pattern is the regular expression the number without its country code must match in full.
+	Pattern string
+	// Checksum validates the check digits of a number without its country code that matched Pattern.
+	// Optional, numbers are only validated by Pattern without it.
+	Checksum func(n string) bool
+	// Normalize is applied to the compact number without its country code by Normalize, e.g. to strip a suffix.
+	// It may be applied more than once to the same number. Optional.
+	Normalize func(n string) string
+	// LookupService is used to check whether numbers exist. Optional, GB numbers are looked up with the UK VAT API,
+	// CH numbers with the UID register, NO numbers with Enhetsregisteret and all others with VIES without it.
+	LookupService LookupServiceInterface
+	// Format returns the display form of a number, given the country code and the number without it. Optional,
+	// numbers are displayed in their compact form without it.
+	Format func(countryCode, n string) string
+}
+
+// countryRule is a registered CountryRule with its compiled pattern
+type countryRule struct {
+	CountryRule
+	pattern *regexp.Regexp
+}
+
+var (
+	countryRulesMu sync.RWMutex
+	countryRules   = make(map[string]*countryRule)
+)
+
+// RegisterCountry adds the rule for a country, replacing the existing rule if the country is already supported.
+// It is safe for concurrent use, but rules are meant to be registered when a program starts.
+func RegisterCountry(rule CountryRule) error {
+	if len(rule.CountryCode) != 2 || rule.CountryCode[0] < 'A' || rule.CountryCode[0] > 'Z' ||
+		rule.CountryCode[1] < 'A' || rule.CountryCode[1] > 'Z' {
+		return ErrInvalidCountryCode
+	}
+	if rule.Pattern == "" {
+		return errors.New("vat: country rule has no pattern")
+	}
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", rule.Pattern))
+	if err != nil {
+		return fmt.Errorf("vat: invalid pattern for %s: %w", rule.CountryCode, err)
+	}
+
+	countryRulesMu.Lock()
+	defer countryRulesMu.Unlock()
+	countryRules[rule.CountryCode] = &countryRule{CountryRule: rule, pattern: pattern}
+	return nil
+}
+
+// SupportedCountries returns the country codes of all countries that have a rule, in alphabetical order.
+func SupportedCountries() []string {
+	countryRulesMu.RLock()
+	defer countryRulesMu.RUnlock()
+
+	codes := make([]string, 0, len(countryRules))
+	for cc := range countryRules {
+		codes = append(codes, cc)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// countryRuleFor returns the rule registered for a country code
+func countryRuleFor(countryCode string) (*countryRule, bool) {
+	countryRulesMu.RLock()
+	defer countryRulesMu.RUnlock()
+
+	rule, ok := countryRules[countryCode]
+	return rule, ok
+}
+
+// defaultCountryRules are the countries supported out of the box
+var defaultCountryRules = []CountryRule{
+	{CountryCode: "AT", Pattern: `U[A-Z0-9]{8}`, Checksum: checksumAT},
+	{CountryCode: "BE", Pattern: `0[0-9]{9}|[0-9]{10}`, Checksum: checksumBE, Format: formatDisplayBE},
+	{CountryCode: "BG", Pattern: `[0-9]{9,10}`, Checksum: checksumBG},
+	// Swiss UIDs, the MWST, TVA or IVA suffix that marks them as VAT numbers is stripped by Normalize
+	{
+		CountryCode: "CH",
+		Pattern:     `E[0-9]{9}`,
+		Checksum:    checksumCH,
+		Normalize:   normalizeCH,
+		Format:      formatDisplayCH,
+	},
+	{CountryCode: "CY", Pattern: `[0-9]{8}[A-Z]`, Checksum: checksumCY},
+	{CountryCode: "CZ", Pattern: `[0-9]{8,10}`, Checksum: checksumCZ},
+	{CountryCode: "DE", Pattern: `[0-9]{9}`, Checksum: checksumDE},
+	{CountryCode: "DK", Pattern: `[0-9]{8}`, Checksum: checksumDK, Format: formatDisplayDK},
+	{CountryCode: "EE", Pattern: `[0-9]{9}`, Checksum: checksumEE},
+	{CountryCode: "EL", Pattern: `[0-9]{9}`, Checksum: checksumEL},
+	{CountryCode: "ES", Pattern: `[A-Z][0-9]{7}[A-Z]|[0-9]{8}[A-Z]|[A-Z][0-9]{8}`, Checksum: checksumES},
+	{CountryCode: "FI", Pattern: `[0-9]{8}`, Checksum: checksumFI},
+	{CountryCode: "FR", Pattern: `[A-Z0-9]{2}[0-9]{9}`, Checksum: checksumFR, Format: formatDisplayFR},
+	// GB numbers have 9 digits, or 12 for branch traders. Government departments use GD000 up to GD499 and
+	// health authorities HA500 up to HA999, see GBVATNumber.
+	{
+		CountryCode: "GB",
+		Pattern:     `[0-9]{9}|[0-9]{12}|GD[0-4][0-9]{2}|HA[5-9][0-9]{2}`,
+		Checksum:    checksumGB,
+		Format:      formatDisplayGB,
+	},
+	{CountryCode: "HR", Pattern: `[0-9]{11}`, Checksum: checksumHR},
+	{CountryCode: "HU", Pattern: `[0-9]{8}`, Checksum: checksumHU},
+	{CountryCode: "IE", Pattern: `[A-Z0-9]{7}[A-Z]|[A-Z0-9]{7}[A-W][A-I]`, Checksum: checksumIE},
+	{CountryCode: "IT", Pattern: `[0-9]{11}`, Checksum: checksumIT},
+	{CountryCode: "LT", Pattern: `[0-9]{9}|[0-9]{12}`, Checksum: checksumLT},
+	{CountryCode: "LU", Pattern: `[0-9]{8}`, Checksum: checksumLU},
+	{CountryCode: "LV", Pattern: `[0-9]{11}`, Checksum: checksumLV},
+	{CountryCode: "MT", Pattern: `[0-9]{8}`, Checksum: checksumMT},
+	{CountryCode: "NL", Pattern: `[0-9]{9}B[0-9]{2}`, Checksum: checksumNL},
+	// Norwegian organisation numbers, the MVA suffix that marks them as VAT numbers is stripped by Normalize
+	{CountryCode: "NO", Pattern: `[0-9]{9}`, Checksum: checksumNO, Normalize: normalizeNO, Format: formatDisplayNO},
+	{CountryCode: "PL", Pattern: `[0-9]{10}`, Checksum: checksumPL, Format: formatDisplayPL},
+	{CountryCode: "PT", Pattern: `[0-9]{9}`, Checksum: checksumPT},
+	{CountryCode: "RO", Pattern: `[0-9]{2,10}`, Checksum: checksumRO},
+	{CountryCode: "SE", Pattern: `[0-9]{12}`, Checksum: checksumSE},
+	{CountryCode: "SI", Pattern: `[0-9]{8}`, Checksum: checksumSI},
+	{CountryCode: "SK", Pattern: `[0-9]{10}`, Checksum: checksumSK},
+	// Northern Ireland, same format as GB
+	{CountryCode: "XI", Pattern: `[0-9]{9}|[0-9]{12}`, Checksum: checksumGB, Format: formatDisplayGB},
+}
+
+func init() {
+	for _, rule := range defaultCountryRules {
+		if err := RegisterCountry(rule); err != nil {
+			panic(err)
+		}
+	}
+}
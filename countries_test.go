@@ -0,0 +1,87 @@
+package vat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestSupportedCountries(t *testing.T) {
+	countries := SupportedCountries()
+	if len(countries) != len(defaultCountryRules) {
+		t.Errorf("Expected %d countries, got %d: %v", len(defaultCountryRules), len(countries), countries)
+	}
+	for i := 1; i < len(countries); i++ {
+		if countries[i-1] >= countries[i] {
+			t.Errorf("Expected countries in alphabetical order, got %v", countries)
+			break
+		}
+	}
+}
+
+func TestRegisterCountry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := NewMockLookupServiceInterface(ctrl)
+	err := RegisterCountry(CountryRule{
+		CountryCode:   "ZZ",
+		Pattern:       `[0-9]{6}`,
+		Checksum:      func(n string) bool { return n[5] == '0' },
+		Normalize:     func(n string) string { return strings.TrimSuffix(n, "X") },
+		LookupService: mockService,
+		Format:        func(cc, n string) string { return cc + " " + n[0:3] + " " + n[3:] },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterCountry("ZZ")
+
+	compact, display := Normalize("zz 123-450X")
+	if compact != "ZZ123450" || display != "ZZ 123 450" {
+		t.Errorf("Unexpected normalized number %q, %q", compact, display)
+	}
+	if err = ValidateFormat("ZZ123451X"); !errors.Is(err, ErrInvalidVATNumberChecksum) {
+		t.Errorf("Expected <%v>, got <%v>", ErrInvalidVATNumberChecksum, err)
+	}
+	if err = ValidateFormat("ZZ12345X"); !errors.Is(err, ErrInvalidVATNumberFormat) {
+		t.Errorf("Expected <%v>, got <%v>", ErrInvalidVATNumberFormat, err)
+	}
+
+	mockService.EXPECT().Validate("ZZ123450", ValidatorOpts{}).Return(nil)
+	if err = Validate("ZZ123450X"); err != nil {
+		t.Errorf("Expected <nil>, got <%v>", err)
+	}
+}
+
+func TestRegisterCountryErrors(t *testing.T) {
+	tests := []CountryRule{
+		{CountryCode: "Z", Pattern: `[0-9]{6}`},
+		{CountryCode: "zz", Pattern: `[0-9]{6}`},
+		{CountryCode: "ZZ"},
+		{CountryCode: "ZZ", Pattern: `[0-9`},
+	}
+	for _, rule := range tests {
+		if err := RegisterCountry(rule); err == nil {
+			unregisterCountry(rule.CountryCode)
+			t.Errorf("Expected an error for %+v", rule)
+		}
+	}
+}
+
+func TestPatternsAreAnchored(t *testing.T) {
+	// the alternatives of the ES pattern used to only be anchored at one end
+	for _, vatNumber := range []string{"ESX5253868RXX", "ESXXB58378431"} {
+		if err := ValidateFormat(vatNumber, ValidatorOpts{SkipChecksum: true}); !errors.Is(err, ErrInvalidVATNumberFormat) {
+			t.Errorf("Expected <%v> for %v, got <%v>", ErrInvalidVATNumberFormat, vatNumber, err)
+		}
+	}
+}
+
+func unregisterCountry(countryCode string) {
+	countryRulesMu.Lock()
+	defer countryRulesMu.Unlock()
+	delete(countryRules, countryCode)
+}
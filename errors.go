@@ -1,13 +1,21 @@
 package vat
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
 // ErrInvalidVATNumberFormat will be returned if a VAT with an invalid format is given
 var ErrInvalidVATNumberFormat = errors.New("vat: VAT number format is invalid")
 
+// ErrInvalidVATNumberChecksum will be returned if a VAT number has the right format but its check digits are wrong.
+// It wraps ErrInvalidVATNumberFormat.
+var ErrInvalidVATNumberChecksum = fmt.Errorf("%w: check digits are incorrect", ErrInvalidVATNumberFormat)
+
 // ErrVATNumberNotFound will be returned if the given VAT number is not found in the external lookup service
 var ErrVATNumberNotFound = errors.New("vat: number not found as an existing active VAT number")
 
@@ -27,6 +35,27 @@ func (e ErrServiceUnavailable) Error() string {
 	return fmt.Sprintf("vat: service is unreachable: %v", e.Err)
 }
 
+// Unwrap returns the underlying error, e.g. context.Canceled if the call was cancelled
+func (e ErrServiceUnavailable) Unwrap() error {
+	return e.Err
+}
+
+// Is makes any ErrServiceUnavailable match, regardless of the underlying error
+func (e ErrServiceUnavailable) Is(target error) bool {
+	_, ok := target.(ErrServiceUnavailable)
+	return ok
+}
+
+// Retryable returns whether the same call may succeed if it is made again.
+// If the underlying error tells whether it is retryable that is used, otherwise only cancelled calls are not.
+func (e ErrServiceUnavailable) Retryable() bool {
+	var r interface{ Retryable() bool }
+	if errors.As(e.Err, &r) {
+		return r.Retryable()
+	}
+	return !errors.Is(e.Err, context.Canceled)
+}
+
 // ErrUnableToGenerateUKAccessToken will be returned if the UK API Access token could not be generated
 type ErrUnableToGenerateUKAccessToken struct {
 	Err error
@@ -37,7 +66,244 @@ func (e ErrUnableToGenerateUKAccessToken) Error() string {
 	return fmt.Sprintf("vat: Error generating UK API Access token: %v", e.Err)
 }
 
+// Unwrap returns the underlying error, e.g. a *UKAPIError if the UK VAT API refused to generate the token
+func (e ErrUnableToGenerateUKAccessToken) Unwrap() error {
+	return e.Err
+}
+
 // ErrMissingUKAccessToken will be returned if the UK API Access token is missing
 var ErrMissingUKAccessToken = errors.New(
 	"vat: missing UK API Access token. Run `vat.GenerateUKAccessToken` to generate one",
 )
+
+// missingUKAccessTokenError is returned when no UK access token was passed in and one couldn't be generated.
+// It matches ErrMissingUKAccessToken and unwraps to the error that prevented the token from being generated.
+type missingUKAccessTokenError struct {
+	Err error
+}
+
+// Error returns the error message
+func (e missingUKAccessTokenError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrMissingUKAccessToken, e.Err)
+}
+
+// Unwrap returns the error that prevented the token from being generated
+func (e missingUKAccessTokenError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the error match ErrMissingUKAccessToken
+func (e missingUKAccessTokenError) Is(target error) bool {
+	return target == ErrMissingUKAccessToken
+}
+
+// ukRequesterVRNError is returned when the requester VRN passed in ValidatorOpts.UKRequesterVRN is invalid.
+// It unwraps to the reason, e.g. ErrInvalidVATNumberChecksum.
+type ukRequesterVRNError struct {
+	Err error
+}
+
+// Error returns the error message
+func (e ukRequesterVRNError) Error() string {
+	return fmt.Sprintf("vat: invalid UK requester VRN: %v", e.Err)
+}
+
+// Unwrap returns the reason the requester VRN is invalid
+func (e ukRequesterVRNError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUKAccessTokenRejected is matched by a *UKAPIError when the UK VAT API rejected the access token,
+// because it is invalid, expired or lacks the required scope.
+var ErrUKAccessTokenRejected = errors.New("vat: UK API Access token was rejected")
+
+// UKAPIError will be returned when the UK VAT API answers with an error.
+// It matches ErrInvalidVATNumberFormat for bad requests, ErrUKAccessTokenRejected for authentication failures and
+// ErrServiceUnavailable for rate limits and server errors when compared with errors.Is. Rate limits and server errors
+// are returned wrapped in ErrRateLimited and ErrServiceUnavailable respectively.
+type UKAPIError struct {
+	StatusCode int
+	// Code is the error code returned by the API, e.g. INVALID_REQUEST or INVALID_CREDENTIALS
+	Code string
+	// Message holds the message returned by the API
+	Message string
+}
+
+// Error returns the error message
+func (e *UKAPIError) Error() string {
+	switch {
+	case e.Code != "" && e.Message != "":
+		return fmt.Sprintf("vat: UK VAT API error %d %s: %s", e.StatusCode, e.Code, e.Message)
+	case e.Code != "" || e.Message != "":
+		return fmt.Sprintf("vat: UK VAT API error %d: %s", e.StatusCode, e.Code+e.Message)
+	default:
+		return fmt.Sprintf("vat: UK VAT API error %d", e.StatusCode)
+	}
+}
+
+// Unauthorized returns whether the API rejected the access token
+func (e *UKAPIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// Temporary returns whether the error is caused by a rate limit or a problem on the side of the API
+func (e *UKAPIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Retryable returns whether the same request may succeed if it is made again later
+func (e *UKAPIError) Retryable() bool {
+	return e.Temporary()
+}
+
+// Is makes the error match the error values this package returned for it before UK VAT API errors were typed
+func (e *UKAPIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidVATNumberFormat:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUKAccessTokenRejected:
+		return e.Unauthorized()
+	}
+	if _, ok := target.(ErrServiceUnavailable); ok {
+		return e.Temporary()
+	}
+	return false
+}
+
+// ViesFaultCode is an error code returned by VIES
+type ViesFaultCode string
+
+// Error codes returned by VIES
+const (
+	ViesInvalidInput               ViesFaultCode = "INVALID_INPUT"
+	ViesInvalidRequesterInfo       ViesFaultCode = "INVALID_REQUESTER_INFO"
+	ViesServiceUnavailable         ViesFaultCode = "SERVICE_UNAVAILABLE"
+	ViesMSUnavailable              ViesFaultCode = "MS_UNAVAILABLE"
+	ViesTimeout                    ViesFaultCode = "TIMEOUT"
+	ViesVATBlocked                 ViesFaultCode = "VAT_BLOCKED"
+	ViesIPBlocked                  ViesFaultCode = "IP_BLOCKED"
+	ViesGlobalMaxConcurrentReq     ViesFaultCode = "GLOBAL_MAX_CONCURRENT_REQ"
+	ViesGlobalMaxConcurrentReqTime ViesFaultCode = "GLOBAL_MAX_CONCURRENT_REQ_TIME"
+	ViesMSMaxConcurrentReq         ViesFaultCode = "MS_MAX_CONCURRENT_REQ"
+	ViesMSMaxConcurrentReqTime     ViesFaultCode = "MS_MAX_CONCURRENT_REQ_TIME"
+	ViesUnknownFault               ViesFaultCode = "UNKNOWN"
+)
+
+// ViesFaultError will be returned when VIES answers with a fault instead of a result.
+// It matches ErrInvalidVATNumberFormat for INVALID_INPUT and ErrServiceUnavailable for temporary faults
+// when compared with errors.Is.
+type ViesFaultError struct {
+	Code ViesFaultCode
+	// Message holds the fault string as returned by VIES
+	Message string
+}
+
+// Error returns the error message
+func (e *ViesFaultError) Error() string {
+	if e.Message != "" && e.Message != string(e.Code) {
+		return fmt.Sprintf("vat: VIES fault %s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("vat: VIES fault %s", e.Code)
+}
+
+// Temporary returns whether the fault is caused by a temporary condition at VIES or a member state,
+// such as an outage or too many concurrent requests.
+func (e *ViesFaultError) Temporary() bool {
+	switch e.Code {
+	case ViesServiceUnavailable, ViesMSUnavailable, ViesTimeout,
+		ViesGlobalMaxConcurrentReq, ViesGlobalMaxConcurrentReqTime,
+		ViesMSMaxConcurrentReq, ViesMSMaxConcurrentReqTime:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retryable returns whether the same request may succeed if it is made again later.
+// Faults about the input or a blocked requester are not retryable.
+func (e *ViesFaultError) Retryable() bool {
+	return e.Temporary()
+}
+
+// Is makes the fault match the error values this package returned for it before faults were typed
+func (e *ViesFaultError) Is(target error) bool {
+	if target == ErrInvalidVATNumberFormat {
+		return e.Code == ViesInvalidInput
+	}
+	if _, ok := target.(ErrServiceUnavailable); ok {
+		return e.Temporary()
+	}
+	return false
+}
+
+// parseViesFaultCode maps a fault string returned by VIES to its code
+func parseViesFaultCode(s string) ViesFaultCode {
+	code := ViesFaultCode(strings.ToUpper(strings.TrimSpace(s)))
+	switch code {
+	case ViesInvalidInput, ViesInvalidRequesterInfo, ViesServiceUnavailable, ViesMSUnavailable, ViesTimeout,
+		ViesVATBlocked, ViesIPBlocked, ViesGlobalMaxConcurrentReq, ViesGlobalMaxConcurrentReqTime,
+		ViesMSMaxConcurrentReq, ViesMSMaxConcurrentReqTime:
+		return code
+	default:
+		return ViesUnknownFault
+	}
+}
+
+// ErrMemberStateUnavailable will be returned when VIES reports that the member state's back end the VAT number
+// would be checked against is unavailable. It matches ErrServiceUnavailable when compared with errors.Is.
+type ErrMemberStateUnavailable struct {
+	Country string
+}
+
+// Error returns the error message
+func (e ErrMemberStateUnavailable) Error() string {
+	return fmt.Sprintf("vat: VIES reports member state %s is unavailable", e.Country)
+}
+
+// Is makes the error match ErrServiceUnavailable
+func (e ErrMemberStateUnavailable) Is(target error) bool {
+	_, ok := target.(ErrServiceUnavailable)
+	return ok
+}
+
+// Temporary returns true, member states usually come back within minutes
+func (e ErrMemberStateUnavailable) Temporary() bool {
+	return true
+}
+
+// Retryable returns true, the same request may succeed once the member state is available again
+func (e ErrMemberStateUnavailable) Retryable() bool {
+	return true
+}
+
+// ErrRateLimited will be returned when an external service rejects a call because its rate limit was hit.
+// It matches ErrServiceUnavailable when compared with errors.Is.
+type ErrRateLimited struct {
+	// RetryAfter is how long the service asked to wait before the next call, or zero if it didn't say
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error returns the error message
+func (e ErrRateLimited) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("vat: rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("vat: rate limited: %v", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e ErrRateLimited) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the error match ErrServiceUnavailable
+func (e ErrRateLimited) Is(target error) bool {
+	_, ok := target.(ErrServiceUnavailable)
+	return ok
+}
+
+// Retryable returns true, the call may succeed once RetryAfter has passed
+func (e ErrRateLimited) Retryable() bool {
+	return true
+}
@@ -0,0 +1,219 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const viesFaultResponse = `<env:Envelope xmlns:env="http://schemas.xmlsoap.org/soap/envelope/">
+<env:Header/>
+<env:Body>
+  <env:Fault>
+    <faultcode>env:Server</faultcode>
+    <faultstring>%s</faultstring>
+  </env:Fault>
+</env:Body>
+</env:Envelope>`
+
+func TestViesFaultError(t *testing.T) {
+	var faultTests = []struct {
+		faultString        string
+		code               ViesFaultCode
+		retryable          bool
+		invalidFormat      bool
+		serviceUnavailable bool
+	}{
+		{"INVALID_INPUT", ViesInvalidInput, false, true, false},
+		{"INVALID_REQUESTER_INFO", ViesInvalidRequesterInfo, false, false, false},
+		{"SERVICE_UNAVAILABLE", ViesServiceUnavailable, true, false, true},
+		{"MS_UNAVAILABLE", ViesMSUnavailable, true, false, true},
+		{"TIMEOUT", ViesTimeout, true, false, true},
+		{"VAT_BLOCKED", ViesVATBlocked, false, false, false},
+		{"IP_BLOCKED", ViesIPBlocked, false, false, false},
+		{"GLOBAL_MAX_CONCURRENT_REQ", ViesGlobalMaxConcurrentReq, true, false, true},
+		{"GLOBAL_MAX_CONCURRENT_REQ_TIME", ViesGlobalMaxConcurrentReqTime, true, false, true},
+		{"MS_MAX_CONCURRENT_REQ", ViesMSMaxConcurrentReq, true, false, true},
+		{"MS_MAX_CONCURRENT_REQ_TIME", ViesMSMaxConcurrentReqTime, true, false, true},
+		{"SOMETHING_NEW", ViesUnknownFault, false, false, false},
+	}
+
+	var faultString string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, viesFaultResponse, faultString)
+	}))
+	defer server.Close()
+
+	c := NewClient(WithViesURL(server.URL))
+	for _, test := range faultTests {
+		faultString = test.faultString
+		err := c.ValidateExists("BE0472429986")
+
+		var fault *ViesFaultError
+		if !errors.As(err, &fault) {
+			t.Errorf("Expected ViesFaultError for %s, got <%v>", test.faultString, err)
+			continue
+		}
+		if fault.Code != test.code {
+			t.Errorf("Expected code %s for %s, got %s", test.code, test.faultString, fault.Code)
+		}
+		if fault.Retryable() != test.retryable {
+			t.Errorf("Expected Retryable() to be %v for %s", test.retryable, test.faultString)
+		}
+		if errors.Is(err, ErrInvalidVATNumberFormat) != test.invalidFormat {
+			t.Errorf("Expected errors.Is(ErrInvalidVATNumberFormat) to be %v for %s", test.invalidFormat, test.faultString)
+		}
+		if errors.Is(err, ErrServiceUnavailable{}) != test.serviceUnavailable {
+			t.Errorf("Expected errors.Is(ErrServiceUnavailable) to be %v for %s",
+				test.serviceUnavailable, test.faultString)
+		}
+	}
+}
+
+func TestViesBadResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("<html>Bad Gateway"))
+	}))
+	defer server.Close()
+
+	err := NewClient(WithViesURL(server.URL)).ValidateExists("BE0472429986")
+	if !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected ErrServiceUnavailable, got <%v>", err)
+	}
+}
+
+func TestUKAPIError(t *testing.T) {
+	var errorTests = []struct {
+		status             int
+		body               string
+		code               string
+		retryable          bool
+		invalidFormat      bool
+		tokenRejected      bool
+		serviceUnavailable bool
+	}{
+		{400, `{"code":"INVALID_REQUEST","message":"Invalid targetVrn - Vrn parameters should be 9 or 12 digits"}`,
+			"INVALID_REQUEST", false, true, false, false},
+		{401, `{"code":"MISSING_CREDENTIALS","message":"Authentication information is not provided"}`,
+			"MISSING_CREDENTIALS", false, false, true, false},
+		{403, `{"code":"INVALID_SCOPE","message":"Cannot access the required resource"}`,
+			"INVALID_SCOPE", false, false, true, false},
+		{422, `{"code":"UNPROCESSABLE_ENTITY","message":"The request could not be processed"}`,
+			"UNPROCESSABLE_ENTITY", false, false, false, false},
+		{429, `{"code":"MESSAGE_THROTTLED_OUT","message":"The request for the API is throttled"}`,
+			"MESSAGE_THROTTLED_OUT", true, false, false, true},
+		{500, `{"code":"INTERNAL_SERVER_ERROR","message":"Internal server error"}`,
+			"INTERNAL_SERVER_ERROR", true, false, false, true},
+		{503, `<html>Service Unavailable</html>`, "", true, false, false, true},
+	}
+
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL))
+	// without credentials the token isn't refreshed after it was rejected
+	opts := ValidatorOpts{UKAccessToken: &UKAccessToken{Token: "abc", ExpiresAt: time.Now().Add(time.Hour)}}
+	for _, test := range errorTests {
+		status, body = test.status, test.body
+		err := c.ValidateExists("GB553557819", opts)
+
+		var apiErr *UKAPIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("Expected UKAPIError for %d, got <%v>", test.status, err)
+			continue
+		}
+		if apiErr.StatusCode != test.status || apiErr.Code != test.code {
+			t.Errorf("Expected %d %s, got %d %s", test.status, test.code, apiErr.StatusCode, apiErr.Code)
+		}
+		if IsRetryable(err) != test.retryable {
+			t.Errorf("Expected IsRetryable() to be %v for %d", test.retryable, test.status)
+		}
+		if errors.Is(err, ErrInvalidVATNumberFormat) != test.invalidFormat {
+			t.Errorf("Expected errors.Is(ErrInvalidVATNumberFormat) to be %v for %d", test.invalidFormat, test.status)
+		}
+		if errors.Is(err, ErrUKAccessTokenRejected) != test.tokenRejected {
+			t.Errorf("Expected errors.Is(ErrUKAccessTokenRejected) to be %v for %d", test.tokenRejected, test.status)
+		}
+		if errors.Is(err, ErrServiceUnavailable{}) != test.serviceUnavailable {
+			t.Errorf("Expected errors.Is(ErrServiceUnavailable) to be %v for %d", test.serviceUnavailable, test.status)
+		}
+	}
+
+	status, body = http.StatusTooManyRequests, `{"code":"MESSAGE_THROTTLED_OUT"}`
+	var rateLimited ErrRateLimited
+	if err := c.ValidateExists("GB553557819", opts); !errors.As(err, &rateLimited) {
+		t.Errorf("Expected <%v>, got <%v>", ErrRateLimited{}, err)
+	}
+}
+
+func TestUKAccessTokenRefresh(t *testing.T) {
+	var tokens int32
+	accepted := "token-2"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			if r.FormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"invalid client secret"}`))
+				return
+			}
+			n := atomic.AddInt32(&tokens, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":14400}`, n)
+		default:
+			if r.Header.Get("Authorization") != "Bearer "+accepted {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"code":"INVALID_CREDENTIALS","message":"Invalid Authentication information provided"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	opts := ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"}
+	ts := NewUKTokenSource(nil, opts)
+	c := NewClient(WithUKURL(server.URL), WithUKTokenSource(ts))
+
+	// the rejected token is replaced once
+	if err := c.ValidateExists("GB553557819"); err != nil {
+		t.Errorf("Expected <nil>, got <%v>", err)
+	}
+	if token, err := ts.get(context.Background(), c); err != nil || token.Token != "token-2" {
+		t.Errorf("Expected token-2 to be cached, got %+v <%v>", token, err)
+	}
+
+	// the token is only refreshed once per lookup
+	accepted = "none"
+	if err := c.ValidateExists("GB553557819"); !errors.Is(err, ErrUKAccessTokenRejected) {
+		t.Errorf("Expected <%v>, got <%v>", ErrUKAccessTokenRejected, err)
+	}
+	if n := atomic.LoadInt32(&tokens); n != 3 {
+		t.Errorf("Expected 3 tokens to be generated, got %d", n)
+	}
+
+	// the reason a token couldn't be generated is kept
+	err := NewClient(WithUKURL(server.URL)).ValidateExists("GB553557819", ValidatorOpts{
+		UKClientID:     "id",
+		UKClientSecret: "wrong",
+	})
+	var genErr ErrUnableToGenerateUKAccessToken
+	if !errors.Is(err, ErrMissingUKAccessToken) || !errors.As(errors.Unwrap(err), &genErr) {
+		t.Fatalf("Expected <%v> caused by ErrUnableToGenerateUKAccessToken, got <%v>", ErrMissingUKAccessToken, err)
+	}
+	var apiErr *UKAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "invalid_client" || apiErr.Message != "invalid client secret" {
+		t.Errorf("Expected the OAuth error to be decoded, got <%v>", err)
+	}
+}
@@ -0,0 +1,96 @@
+package vat
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GBVATNumberKind is the kind of registration a GB VAT number belongs to.
+type GBVATNumberKind int
+
+const (
+	// GBStandard is a standard 9 digit VAT registration number.
+	GBStandard GBVATNumberKind = iota
+	// GBBranch is a 12 digit number of a branch trader: the 9 digit registration number followed by a branch suffix.
+	GBBranch
+	// GBGovernmentDepartment is a number of a government department, GD000 up to GD499.
+	GBGovernmentDepartment
+	// GBHealthAuthority is a number of a health authority, HA500 up to HA999.
+	GBHealthAuthority
+)
+
+// String returns a description of the kind of registration.
+func (k GBVATNumberKind) String() string {
+	switch k {
+	case GBStandard:
+		return "standard"
+	case GBBranch:
+		return "branch"
+	case GBGovernmentDepartment:
+		return "government department"
+	case GBHealthAuthority:
+		return "health authority"
+	default:
+		return "unknown"
+	}
+}
+
+// CanLookup returns whether numbers of this kind can be looked up with the UK VAT API. Government departments and
+// health authorities aren't in the register the API checks against.
+func (k GBVATNumberKind) CanLookup() bool {
+	return k == GBStandard || k == GBBranch
+}
+
+// GBVATNumber describes a GB VAT number and the kind of registration it belongs to.
+type GBVATNumber struct {
+	// Number is the registration number without the country code and branch suffix, e.g. "999999973" or "GD001".
+	Number string
+	// Branch is the 3 digit branch suffix of a GBBranch number.
+	Branch string
+	Kind   GBVATNumberKind
+}
+
+// ParseGBVATNumber validates the format and check digits of a GB VAT number and returns what kind of registration
+// it belongs to. Northern Ireland numbers starting with XI are accepted as well. The number is normalized first, so
+// it may contain spaces, punctuation and a VAT label.
+func ParseGBVATNumber(vatNumber string) (*GBVATNumber, error) {
+	vatNumber = normalizeVATNumber(vatNumber)
+	if len(vatNumber) < 3 {
+		return nil, ErrInvalidVATNumberFormat
+	}
+	if cc := vatNumber[0:2]; cc != "GB" && cc != "XI" {
+		return nil, ErrInvalidCountryCode
+	}
+	if err := ValidateFormat(vatNumber); err != nil {
+		return nil, err
+	}
+	return parseGBVATNumber(vatNumber[2:]), nil
+}
+
+// gbNonLookupPattern matches the numbers of government departments and health authorities
+var gbNonLookupPattern = regexp.MustCompile(`^(?:GD[0-4][0-9]{2}|HA[5-9][0-9]{2})$`)
+
+// parseGBVATNumber splits a GB VAT number without its country code, assuming its format is valid.
+func parseGBVATNumber(n string) *GBVATNumber {
+	switch {
+	case strings.HasPrefix(n, "GD"):
+		return &GBVATNumber{Number: n, Kind: GBGovernmentDepartment}
+	case strings.HasPrefix(n, "HA"):
+		return &GBVATNumber{Number: n, Kind: GBHealthAuthority}
+	case len(n) == 12:
+		return &GBVATNumber{Number: n[:9], Branch: n[9:], Kind: GBBranch}
+	default:
+		return &GBVATNumber{Number: n, Kind: GBStandard}
+	}
+}
+
+// checksumGB validates the check digits of a GB VAT number. The first 9 digits of standard and branch numbers are
+// validated with the mod 97 algorithm or the newer mod 9755 one.
+// Government department and health authority numbers don't have check digits.
+func checksumGB(n string) bool {
+	if !isDigits(n) {
+		return true
+	}
+	sum := weightedSum(n, 8, 7, 6, 5, 4, 3, 2, 10, 1) % 97
+	return sum == 0 || sum == 42
+}
@@ -0,0 +1,86 @@
+package vat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseGBVATNumber(t *testing.T) {
+	tests := []struct {
+		vatNumber     string
+		expected      GBVATNumber
+		expectedError error
+	}{
+		{"GB999999973", GBVATNumber{Number: "999999973", Kind: GBStandard}, nil},
+		{"gb999999973", GBVATNumber{Number: "999999973", Kind: GBStandard}, nil},
+		{"XI999999973", GBVATNumber{Number: "999999973", Kind: GBStandard}, nil},
+		{"GB 999 9999 73", GBVATNumber{Number: "999999973", Kind: GBStandard}, nil},
+		{"VAT: GB999999973", GBVATNumber{Number: "999999973", Kind: GBStandard}, nil},
+		{"GB 999 9999 73 001", GBVATNumber{Number: "999999973", Branch: "001", Kind: GBBranch}, nil},
+		{"VAT No. gb gd 001", GBVATNumber{Number: "GD001", Kind: GBGovernmentDepartment}, nil},
+		{"GB999999973001", GBVATNumber{Number: "999999973", Branch: "001", Kind: GBBranch}, nil},
+		{"GBGD001", GBVATNumber{Number: "GD001", Kind: GBGovernmentDepartment}, nil},
+		{"GBHA599", GBVATNumber{Number: "HA599", Kind: GBHealthAuthority}, nil},
+		{"GBGD500", GBVATNumber{}, ErrInvalidVATNumberFormat},
+		{"GBHA499", GBVATNumber{}, ErrInvalidVATNumberFormat},
+		{"GB999999974", GBVATNumber{}, ErrInvalidVATNumberChecksum},
+		{"NL004495445B01", GBVATNumber{}, ErrInvalidCountryCode},
+		{"GB", GBVATNumber{}, ErrInvalidVATNumberFormat},
+	}
+
+	for _, test := range tests {
+		n, err := ParseGBVATNumber(test.vatNumber)
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("Expected <%v> for %v, got <%v>", test.expectedError, test.vatNumber, err)
+			continue
+		}
+		if err == nil && *n != test.expected {
+			t.Errorf("Expected %+v for %v, got %+v", test.expected, test.vatNumber, *n)
+		}
+	}
+}
+
+func TestClientUKKinds(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/oauth/token":
+			_, _ = io.WriteString(w, `{"access_token":"abc","expires_in":14400}`)
+		case "/organisations/vat/check-vat-number/lookup/999999973":
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL))
+	opts := ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"}
+
+	// branch traders are looked up by their registration number
+	r, err := c.Lookup("GB999999973001", opts)
+	if err != nil || !r.Valid || r.VATNumber != "999999973" || r.Service != ServiceHMRC {
+		t.Errorf("Expected valid HMRC result for GB999999973001, got %+v <%v>", r, err)
+	}
+
+	// government departments and health authorities can't be looked up
+	paths = nil
+	for _, vatNumber := range []string{"GBGD001", "GBHA599"} {
+		r, err = c.Lookup(vatNumber, opts)
+		if err != nil || !r.Valid || r.Service != "" {
+			t.Errorf("Expected valid result without service for %v, got %+v <%v>", vatNumber, r, err)
+		}
+	}
+	for _, vatNumber := range []string{"GBHA", "GBGDXYZ", "GBGD500"} {
+		if err = c.ValidateExists(vatNumber, opts); !errors.Is(err, ErrInvalidVATNumberFormat) {
+			t.Errorf("Expected <%v> for %v, got <%v>", ErrInvalidVATNumberFormat, vatNumber, err)
+		}
+	}
+	if len(paths) != 0 {
+		t.Errorf("Expected no calls to the UK VAT API, got %v", paths)
+	}
+}
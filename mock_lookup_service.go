@@ -5,6 +5,7 @@
 package vat
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -33,6 +34,36 @@ func (m *MockLookupServiceInterface) EXPECT() *MockLookupServiceInterfaceMockRec
 	return m.recorder
 }
 
+// Lookup mocks base method.
+func (m *MockLookupServiceInterface) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lookup", vatNumber, opts)
+	ret0, _ := ret[0].(*ValidationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lookup indicates an expected call of Lookup.
+func (mr *MockLookupServiceInterfaceMockRecorder) Lookup(vatNumber, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lookup", reflect.TypeOf((*MockLookupServiceInterface)(nil).Lookup), vatNumber, opts)
+}
+
+// LookupContext mocks base method.
+func (m *MockLookupServiceInterface) LookupContext(ctx context.Context, vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupContext", ctx, vatNumber, opts)
+	ret0, _ := ret[0].(*ValidationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupContext indicates an expected call of LookupContext.
+func (mr *MockLookupServiceInterfaceMockRecorder) LookupContext(ctx, vatNumber, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupContext", reflect.TypeOf((*MockLookupServiceInterface)(nil).LookupContext), ctx, vatNumber, opts)
+}
+
 // Validate mocks base method.
 func (m *MockLookupServiceInterface) Validate(vatNumber string, opts ValidatorOpts) error {
 	m.ctrl.T.Helper()
@@ -46,3 +77,17 @@ func (mr *MockLookupServiceInterfaceMockRecorder) Validate(vatNumber, opts inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockLookupServiceInterface)(nil).Validate), vatNumber, opts)
 }
+
+// ValidateContext mocks base method.
+func (m *MockLookupServiceInterface) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateContext", ctx, vatNumber, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateContext indicates an expected call of ValidateContext.
+func (mr *MockLookupServiceInterfaceMockRecorder) ValidateContext(ctx, vatNumber, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateContext", reflect.TypeOf((*MockLookupServiceInterface)(nil).ValidateContext), ctx, vatNumber, opts)
+}
@@ -0,0 +1,185 @@
+package vat
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// vatLabel matches the labels people put in front of VAT numbers, e.g. "VAT", "USt-IdNr" or "NIF"
+var vatLabel = regexp.MustCompile(
+	`^(?:USTIDNR|USTID|UST|VAT|TVA|BTW|P\.?IVA|IVA|MWST|MVA|MOMS|ALV|N\.?I\.?F|C\.?I\.?F|NIPC|UID)`,
+)
+
+// vatLabelQualifier matches the words that may follow a label, e.g. "Reg" and "No" in "VAT Reg No"
+var vatLabelQualifier = regexp.MustCompile(
+	`^(?:REGISTRATION|REG|IDNR|IDENT|ID|NUMBER|NUMMER|NUMERO|NUM|NO|NR|N°|Nº)`,
+)
+
+// vatLabelSeparator matches what separates the words of a label from each other and from the number
+var vatLabelSeparator = regexp.MustCompile(`^[\s.:#°º/-]*`)
+
+// Normalize cleans up a VAT number as typed or pasted by a person. It returns the compact form that the Validate
+// functions expect, e.g. "NL004495445B01", and a form for display that follows the conventions of the country,
+// e.g. "BE 0403.019.261", see CountryRule.
+//
+// Letters are upper-cased, full-width characters are mapped to their ASCII counterparts, labels like "VAT:" are
+// removed and whitespace, dots and dashes are stripped. The Greek country code GR is mapped to EL, which is the one
+// VIES uses. Normalize doesn't validate the number; numbers without the expected length are displayed compact.
+func Normalize(vatNumber string) (compact, display string) {
+	compact = normalizeVATNumber(vatNumber)
+	return compact, formatDisplay(compact)
+}
+
+// normalizeVATNumber returns the compact form of a VAT number, see Normalize
+func normalizeVATNumber(vatNumber string) string {
+	s := strings.Map(foldWidth, vatNumber)
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = stripVATLabel(s)
+	compact := strings.Map(func(r rune) rune {
+		if r == '.' || unicode.IsSpace(r) || unicode.Is(unicode.Pd, r) || unicode.Is(unicode.Cf, r) || r == '\u2212' {
+			return -1
+		}
+		return r
+	}, s)
+
+	if strings.HasPrefix(compact, "GR") {
+		compact = "EL" + compact[2:]
+	}
+	if len(compact) > 2 {
+		if rule, ok := countryRuleFor(compact[0:2]); ok && rule.Normalize != nil {
+			compact = compact[0:2] + rule.Normalize(compact[2:])
+		}
+	}
+
+	return compact
+}
+
+// stripVATLabel removes a label like "VAT No.:" or "BTW-nummer" from the start of an upper-cased VAT number.
+// NO is both a qualifier and the Norwegian country code; it is kept when it is directly followed by digits or only
+// whitespace and digits, as in "VAT NO 995525828".
+func stripVATLabel(s string) string {
+	label := vatLabel.FindString(s)
+	if label == "" || startsWithLetter(s[len(label):]) {
+		return s
+	}
+
+	rest := s[len(label):]
+	for {
+		rest = rest[len(vatLabelSeparator.FindString(rest)):]
+		qualifier := vatLabelQualifier.FindString(rest)
+		next := rest[len(qualifier):]
+		if qualifier == "" || startsWithLetter(next) {
+			// what follows isn't a qualifier but the number, e.g. "IDE" in "VAT IDE..."
+			return rest
+		}
+		sep := vatLabelSeparator.FindString(next)
+		if qualifier == "NO" && strings.TrimSpace(sep) == "" && len(next) > len(sep) && isDigits(next[len(sep):][:1]) {
+			return rest
+		}
+		rest = next
+	}
+}
+
+// startsWithLetter returns whether s starts with an ASCII letter
+func startsWithLetter(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z'
+}
+
+// formatDisplay returns the display form of a compact VAT number, see CountryRule.Format
+func formatDisplay(compact string) string {
+	if len(compact) > 2 {
+		if rule, ok := countryRuleFor(compact[0:2]); ok && rule.Format != nil {
+			return rule.Format(compact[0:2], compact[2:])
+		}
+	}
+	return compact
+}
+
+// foldWidth maps full-width characters, as typed with East Asian input methods, to their ASCII counterparts
+func foldWidth(r rune) rune {
+	switch {
+	case r >= '\uFF01' && r <= '\uFF5E':
+		return r - '\uFF01' + '!'
+	case r == '\u3000':
+		return ' '
+	}
+	return r
+}
+
+// formatDisplayBE formats a Belgian enterprise number, e.g. "BE 0403.019.261"
+func formatDisplayBE(cc, n string) string {
+	if len(n) != 10 {
+		return cc + n
+	}
+	return cc + " " + n[0:4] + "." + n[4:7] + "." + n[7:]
+}
+
+// normalizeCH strips the suffix that marks a Swiss UID as a VAT number, which is MWST, TVA or IVA depending on the
+// language
+func normalizeCH(n string) string {
+	for _, suffix := range []string{"MWST", "TVA", "IVA"} {
+		if strings.HasSuffix(n, suffix) {
+			return strings.TrimSuffix(n, suffix)
+		}
+	}
+	return n
+}
+
+// formatDisplayCH formats a Swiss VAT number, e.g. "CHE-123.456.789 MWST"
+func formatDisplayCH(cc, n string) string {
+	if len(n) != 10 || n[0] != 'E' {
+		return cc + n
+	}
+	return cc + "E-" + n[1:4] + "." + n[4:7] + "." + n[7:] + " MWST"
+}
+
+// formatDisplayDK formats a Danish CVR number in pairs of digits, e.g. "DK 13 58 56 28"
+func formatDisplayDK(cc, n string) string {
+	if len(n) != 8 {
+		return cc + n
+	}
+	return cc + " " + n[0:2] + " " + n[2:4] + " " + n[4:6] + " " + n[6:]
+}
+
+// formatDisplayFR formats a French VAT number, e.g. "FR 40 303 265 045"
+func formatDisplayFR(cc, n string) string {
+	if len(n) != 11 {
+		return cc + n
+	}
+	return cc + " " + n[0:2] + " " + n[2:5] + " " + n[5:8] + " " + n[8:]
+}
+
+// normalizeNO strips the MVA suffix that marks a Norwegian organisation number as a VAT number
+func normalizeNO(n string) string {
+	return strings.TrimSuffix(n, "MVA")
+}
+
+// formatDisplayNO formats a Norwegian VAT number, e.g. "NO 995 525 828 MVA"
+func formatDisplayNO(cc, n string) string {
+	if len(n) != 9 {
+		return cc + n
+	}
+	return cc + " " + n[0:3] + " " + n[3:6] + " " + n[6:] + " MVA"
+}
+
+// formatDisplayPL formats a Polish NIP, e.g. "PL 856-734-62-15"
+func formatDisplayPL(cc, n string) string {
+	if len(n) != 10 {
+		return cc + n
+	}
+	return cc + " " + n[0:3] + "-" + n[3:6] + "-" + n[6:8] + "-" + n[8:]
+}
+
+// formatDisplayGB formats GB and XI numbers the way HMRC does, e.g. "GB 999 9999 73" or "GB 999 9999 73 001" for
+// a branch trader
+func formatDisplayGB(cc, n string) string {
+	if (len(n) != 9 && len(n) != 12) || !isDigits(n) {
+		return cc + n
+	}
+	display := cc + " " + n[0:3] + " " + n[3:7] + " " + n[7:9]
+	if len(n) == 12 {
+		display += " " + n[9:]
+	}
+	return display
+}
@@ -0,0 +1,64 @@
+package vat
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input   string
+		compact string
+		display string
+	}{
+		{"NL004495445B01", "NL004495445B01", "NL004495445B01"},
+		{"nl 0044.954.45.B01", "NL004495445B01", "NL004495445B01"},
+		{"DE-136 695 976", "DE136695976", "DE136695976"},
+		{"  de136695976\t", "DE136695976", "DE136695976"},
+		{"CHE-123.456.789 MWST", "CHE123456789", "CHE-123.456.789 MWST"},
+		{"che 123 456 789 tva", "CHE123456789", "CHE-123.456.789 MWST"},
+		{"CHE-123.456.789 IVA", "CHE123456789", "CHE-123.456.789 MWST"},
+		{"CHE123456789", "CHE123456789", "CHE-123.456.789 MWST"},
+		{"VAT: BE0403019261", "BE0403019261", "BE 0403.019.261"},
+		{"VAT No. GB 999 9999 73", "GB999999973", "GB 999 9999 73"},
+		{"vat number gb999999973001", "GB999999973001", "GB 999 9999 73 001"},
+		{"USt-IdNr.: DE136695976", "DE136695976", "DE136695976"},
+		{"BTW NL004495445B01", "NL004495445B01", "NL004495445B01"},
+		{"VAT NO GB999999973", "GB999999973", "GB 999 9999 73"},
+		{"VAT Reg No: GB999999973", "GB999999973", "GB 999 9999 73"},
+		{"VAT NO 995 525 828", "NO995525828", "NO 995 525 828 MVA"},
+		{"BTW-nummer NL004495445B01", "NL004495445B01", "NL004495445B01"},
+		{"MwSt.-Nr. CHE-113.690.319", "CHE113690319", "CHE-113.690.319 MWST"},
+		{"NIF ESB58378431", "ESB58378431", "ESB58378431"},
+		{"N.I.F. ESB58378431", "ESB58378431", "ESB58378431"},
+		{"P.IVA IT00743110157", "IT00743110157", "IT00743110157"},
+		{"TVA n° FR40303265045", "FR40303265045", "FR 40 303 265 045"},
+		{"VAT ID: DE136695976", "DE136695976", "DE136695976"},
+		{"GR094259216", "EL094259216", "EL094259216"},
+		{"DK 13 58 56 28", "DK13585628", "DK 13 58 56 28"},
+		{"ＦＲ４０３０３２６５０４５", "FR40303265045", "FR 40 303 265 045"},
+		{"PL\u2011856\u2013734\u201462\u221215", "PL8567346215", "PL 856-734-62-15"},
+		{"\ufeffBE0403019261\u200b", "BE0403019261", "BE 0403.019.261"},
+		{"GBGD001", "GBGD001", "GBGD001"},
+		{"BE123", "BE123", "BE123"},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		compact, display := Normalize(test.input)
+		if compact != test.compact || display != test.display {
+			t.Errorf("Expected %q, %q for %q, got %q, %q", test.compact, test.display, test.input, compact, display)
+		}
+	}
+}
+
+func TestValidateFormatNormalizes(t *testing.T) {
+	vatNumbers := []string{
+		"nl 0044.954.45.B01",
+		"VAT: DE 136-695-976",
+		"GR 094259216",
+		"ＢＥ０４０３０１９２６１",
+	}
+	for _, vatNumber := range vatNumbers {
+		if err := ValidateFormat(vatNumber); err != nil {
+			t.Errorf("Expected <nil> for %q, got <%v>", vatNumber, err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package vat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit configures how fast and how many calls at once a client makes to a lookup service.
+// Calls that exceed the limit wait until they may be made, or until their context is done.
+type RateLimit struct {
+	// RequestsPerSecond is the rate calls are made at on average. Zero means no rate limit.
+	RequestsPerSecond float64
+	// Burst is the number of calls that may be made at once before the rate applies. Defaults to 1.
+	Burst int
+	// MaxConcurrent is the maximum number of calls in flight. Zero means no cap.
+	MaxConcurrent int
+}
+
+// DefaultHMRCRateLimit stays within the default rate limit of 3 requests per second HMRC grants an application.
+var DefaultHMRCRateLimit = RateLimit{RequestsPerSecond: 3, Burst: 3}
+
+// DefaultViesMemberStateRateLimit avoids MS_MAX_CONCURRENT_REQ faults by making at most 2 concurrent calls to VIES
+// for each member state.
+var DefaultViesMemberStateRateLimit = RateLimit{MaxConcurrent: 2}
+
+// WithRateLimit limits the calls the client makes to a lookup service. The service is one of the Service constants,
+// e.g. ServiceHMRC, or the country code of a CountryRule with its own lookup service. Retried calls count as well.
+func WithRateLimit(service string, limit RateLimit) ClientOption {
+	return func(c *Client) {
+		c.getRateLimiters().set(service, limit)
+	}
+}
+
+// WithViesMemberStateRateLimit limits the calls the client makes to VIES for each of the given member states,
+// on top of the limit set for ServiceVIES with WithRateLimit. Without country codes the limit applies to every member
+// state separately; limits set for specific member states take precedence.
+func WithViesMemberStateRateLimit(limit RateLimit, countryCodes ...string) ClientOption {
+	return func(c *Client) {
+		l := c.getRateLimiters()
+		if len(countryCodes) == 0 {
+			l.set(memberStateLimitKey("*"), limit)
+		}
+		for _, cc := range countryCodes {
+			l.set(memberStateLimitKey(strings.ToUpper(cc)), limit)
+		}
+	}
+}
+
+// getRateLimiters returns the rate limiters of the client, creating them if needed. It is only called while the
+// client is being created.
+func (c *Client) getRateLimiters() *rateLimiters {
+	if c.rateLimiters == nil {
+		c.rateLimiters = &rateLimiters{limits: make(map[string]RateLimit), limiters: make(map[string]*rateLimiter)}
+	}
+	return c.rateLimiters
+}
+
+// waitRateLimit blocks until the limits for the service, and for VIES the limits for the member state, allow a call.
+// The returned function must be called once the call is done.
+func (c *Client) waitRateLimit(ctx context.Context, service, countryCode string) (func(), error) {
+	if c.rateLimiters == nil {
+		return func() {}, nil
+	}
+
+	limiters := []*rateLimiter{c.rateLimiters.get(service)}
+	if service == ServiceVIES {
+		limiters = append(limiters, c.rateLimiters.getMemberState(countryCode))
+	}
+	return waitRateLimiters(ctx, limiters)
+}
+
+// waitRateLimiters blocks until each of the limiters, in order, allows a call. Nil limiters are skipped.
+// The returned function must be called once the call is done.
+func waitRateLimiters(ctx context.Context, limiters []*rateLimiter) (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		r, err := l.wait(ctx)
+		if err != nil {
+			release()
+			return nil, ErrServiceUnavailable{Err: err}
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// memberStateLimitKey returns the key the limit of a VIES member state is stored under, * being every member state
+func memberStateLimitKey(countryCode string) string {
+	return ServiceVIES + ":" + countryCode
+}
+
+// rateLimiters holds the configured limits and the limiters that enforce them, which are created on first use
+type rateLimiters struct {
+	limits map[string]RateLimit
+
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+func (r *rateLimiters) set(key string, limit RateLimit) {
+	r.limits[key] = limit
+}
+
+// get returns the limiter for the key, or nil if no limit is set for it
+func (r *rateLimiters) get(key string) *rateLimiter {
+	limit, ok := r.limits[key]
+	if !ok {
+		return nil
+	}
+	return r.limiter(key, limit)
+}
+
+// getMemberState returns the limiter for the VIES member state. Member states without a limit of their own get a
+// limiter of their own with the limit set for every member state.
+func (r *rateLimiters) getMemberState(countryCode string) *rateLimiter {
+	key := memberStateLimitKey(countryCode)
+	limit, ok := r.limits[key]
+	if !ok {
+		if limit, ok = r.limits[memberStateLimitKey("*")]; !ok {
+			return nil
+		}
+	}
+	return r.limiter(key, limit)
+}
+
+// limiter returns the limiter stored under the key, creating it with the limit on first use
+func (r *rateLimiters) limiter(key string, limit RateLimit) *rateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limiters[key]
+	if !ok {
+		l = newRateLimiter(limit)
+		r.limiters[key] = l
+	}
+	return l
+}
+
+// rateLimiter enforces a RateLimit with a token bucket and a semaphore
+type rateLimiter struct {
+	limit RateLimit
+	sem   chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	l := &rateLimiter{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+	if limit.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return l
+}
+
+// wait blocks until a call may be made or the context is done. A slot for a concurrent call is acquired before a
+// token, so that calls waiting for a slot don't use up the rate.
+func (l *rateLimiter) wait(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if d := l.reserve(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.cancelReservation()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// reserve takes a token from the bucket and returns how long to wait until it is available
+func (l *rateLimiter) reserve() time.Duration {
+	if l.limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limit.RequestsPerSecond
+	if burst := float64(l.limit.Burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// cancelReservation returns a token that was reserved for a call that won't be made
+func (l *rateLimiter) cancelReservation() {
+	if l.limit.RequestsPerSecond <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+// rateLimitedService wraps a lookup service and waits for the client's rate limits before every call
+type rateLimitedService struct {
+	next    LookupServiceInterface
+	client  *Client
+	service string
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *rateLimitedService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for waiting and the call to the lookup service.
+func (s *rateLimitedService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the lookup service holds for the given VAT number.
+func (s *rateLimitedService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for waiting and the call to the lookup service.
+func (s *rateLimitedService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ValidatorOpts,
+) (*ValidationResult, error) {
+	var countryCode string
+	if len(vatNumber) >= 2 {
+		countryCode = strings.ToUpper(vatNumber[0:2])
+	}
+	release, err := s.client.waitRateLimit(ctx, s.service, countryCode)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.next.LookupContext(ctx, vatNumber, opts)
+}
@@ -0,0 +1,97 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRate(t *testing.T) {
+	l := newRateLimiter(RateLimit{RequestsPerSecond: 20, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, err := l.wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// the first 2 calls are made right away, the other 4 at 20 per second
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected 6 calls to take about 200ms, took %s", elapsed)
+	}
+}
+
+func TestRateLimiterContext(t *testing.T) {
+	l := newRateLimiter(RateLimit{RequestsPerSecond: 1, MaxConcurrent: 1})
+
+	release, err := l.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for a concurrency slot stops when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected <%v>, got <%v>", context.DeadlineExceeded, err)
+	}
+	release()
+
+	// and so does waiting for a token, which is given back
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected <%v>, got <%v>", context.DeadlineExceeded, err)
+	}
+	if d := l.reserve(); d > time.Second {
+		t.Errorf("Expected the cancelled reservation to be given back, have to wait %s", d)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := newViesCountingServer()
+	defer server.Close()
+
+	c := NewClient(
+		WithViesURL(server.URL),
+		WithRateLimit(ServiceVIES, RateLimit{MaxConcurrent: 3}),
+		WithViesMemberStateRateLimit(DefaultViesMemberStateRateLimit),
+		WithViesMemberStateRateLimit(RateLimit{MaxConcurrent: 1}, "de"),
+	)
+
+	var wg sync.WaitGroup
+	for _, vatNumber := range []string{
+		"BE0472429986", "BE0403019360", "BE0403019459", "BE0403019558",
+		"DE136695976", "DE136695976", "DE136695976",
+	} {
+		wg.Add(1)
+		go func(vatNumber string) {
+			defer wg.Done()
+			if err := c.ValidateExists(vatNumber); err != nil {
+				t.Errorf("Expected <nil> for %s, got <%v>", vatNumber, err)
+			}
+		}(vatNumber)
+	}
+	wg.Wait()
+
+	maxInFlight := server.maxInFlight
+	if maxInFlight["BE"] > 2 || maxInFlight["DE"] > 1 || maxInFlight["BE"]+maxInFlight["DE"] > 3 {
+		t.Errorf("Expected at most 2 BE and 1 DE lookups in flight, got %v", maxInFlight)
+	}
+
+	// lookups that can't be made before the context is done fail
+	c = NewClient(WithViesURL(server.URL), WithRateLimit(ServiceVIES, RateLimit{RequestsPerSecond: 0.1}))
+	if err := c.ValidateExists("BE0472429986"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := c.ValidateExistsContext(ctx, "BE0472429986")
+	if !errors.Is(err, ErrServiceUnavailable{}) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected <%v>, got <%v>", ErrServiceUnavailable{Err: context.DeadlineExceeded}, err)
+	}
+}
@@ -1,6 +1,7 @@
 package vat
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -51,8 +52,13 @@ func (cr *CountryRates) GetRate(level string) (float32, error) {
 
 // GetCountryRates gets the CountryRates struct for a country by its ISO-3166-1-alpha2 country code.
 func GetCountryRates(countryCode string) (CountryRates, error) {
+	return GetCountryRatesContext(context.Background(), countryCode)
+}
+
+// GetCountryRatesContext is like GetCountryRates, but the context is used if the rates need to be fetched.
+func GetCountryRatesContext(ctx context.Context, countryCode string) (CountryRates, error) {
 	var rate CountryRates
-	rates, err := GetRates()
+	rates, err := GetRatesContext(ctx)
 
 	if err != nil {
 		return rate, err
@@ -69,11 +75,16 @@ func GetCountryRates(countryCode string) (CountryRates, error) {
 
 // GetRates returns the in-memory VAT rates
 func GetRates() ([]CountryRates, error) {
+	return GetRatesContext(context.Background())
+}
+
+// GetRatesContext is like GetRates, but the context is used if the rates need to be fetched.
+func GetRatesContext(ctx context.Context) ([]CountryRates, error) {
 	var err error
 
 	mutex.Lock()
 	if countriesRates == nil {
-		countriesRates, err = FetchRates()
+		countriesRates, err = FetchRatesContext(ctx)
 	}
 	mutex.Unlock()
 
@@ -82,13 +93,33 @@ func GetRates() ([]CountryRates, error) {
 
 // FetchRates fetches the latest VAT rates from ibericode/vat-rates and updates the in-memory rates
 func FetchRates() ([]CountryRates, error) {
-	client := http.Client{
-		Timeout: serviceTimeout,
+	return FetchRatesContext(context.Background())
+}
+
+// FetchRatesContext is like FetchRates, but the context is used for the download.
+func FetchRatesContext(ctx context.Context) ([]CountryRates, error) {
+	return defaultClient.FetchRatesContext(ctx)
+}
+
+// FetchRates fetches the latest VAT rates from the client's rates source
+func (c *Client) FetchRates() ([]CountryRates, error) {
+	return c.FetchRatesContext(context.Background())
+}
+
+// FetchRatesContext is like FetchRates, but the context is used for the download.
+func (c *Client) FetchRatesContext(ctx context.Context) ([]CountryRates, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.getRatesURL(), nil)
+	if err != nil {
+		return nil, err
 	}
-	r, err := client.Get("https://raw.githubusercontent.com/ibericode/vat-rates/master/vat-rates.json")
+
+	r, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
 
 	apiResponse := &struct {
 		Details string
@@ -122,3 +153,5 @@ func FetchRates() ([]CountryRates, error) {
 
 	return rates, err
 }
+
+const ratesURL = "https://raw.githubusercontent.com/ibericode/vat-rates/master/vat-rates.json"
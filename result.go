@@ -0,0 +1,50 @@
+package vat
+
+import "time"
+
+// Names of the external services that can produce a ValidationResult.
+const (
+	ServiceVIES  = "VIES"
+	ServiceHMRC  = "HMRC"
+	ServiceUID   = "UID"
+	ServiceBRREG = "BRREG"
+)
+
+// ValidationResult holds the details returned by an external lookup service for a VAT number.
+type ValidationResult struct {
+	CountryCode string
+	VATNumber   string
+	Valid       bool
+	Name        string
+	Address     string
+	RequestDate time.Time
+	// Service is the lookup service that produced the result, e.g. ServiceUID for the Swiss UID register.
+	// It is empty if no service could be asked, which is the case for GB government departments and health authorities.
+	Service string
+
+	// ConsultationNumber identifies the check with the lookup service and serves as proof that it was made.
+	// It is only returned when the requester's own VAT number was part of the check.
+	ConsultationNumber string
+
+	// Attempts is the number of calls it took to get the result from the lookup service
+	Attempts int
+
+	// Cached is set if the result was served from a Cache. CachedAt is when it was retrieved from the lookup service.
+	Cached   bool
+	CachedAt time.Time
+
+	// Counterpart holds the result for the GB number of the same registration when an XI number is checked with
+	// XIRoutingBoth. The number is only valid if the counterpart is valid too.
+	Counterpart *ValidationResult
+}
+
+// validateResult turns the outcome of a lookup into the error returned by the Validate functions.
+func validateResult(r *ValidationResult, err error) error {
+	if err != nil {
+		return err
+	}
+	if !r.Valid || (r.Counterpart != nil && !r.Counterpart.Valid) {
+		return ErrVATNumberNotFound
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how lookups that fail with a retryable error are retried.
+// Errors are retryable if IsRetryable returns true for them.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls made for a single lookup, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time to wait between two attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after every attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes each backoff by up to the given fraction in either direction, e.g. 0.2 for ±20%.
+	Jitter float64
+	// MaxElapsed is the overall deadline for a lookup, including all attempts and backoffs. Zero means no deadline.
+	MaxElapsed time.Duration
+}
+
+// DefaultRetryPolicy is a sensible retry policy for the VIES and UK VAT lookup services.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxElapsed:     time.Minute,
+}
+
+// WithRetryPolicy makes the client retry lookups that fail with a retryable error according to the policy.
+// A Retry-After returned by a lookup service takes precedence over a shorter backoff.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = &p
+	}
+}
+
+// IsRetryable returns whether a lookup that failed with the given error may succeed if it is made again,
+// e.g. because a service was temporarily unavailable or too busy.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	return false
+}
+
+// backoff returns the time to wait after the given attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 - p.Jitter + 2*p.Jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// do calls f until it succeeds, fails with an error that is not retryable, or the policy gives up.
+// It returns the number of attempts made along with the last error.
+func (p RetryPolicy) do(ctx context.Context, f func(ctx context.Context) error) (int, error) {
+	start := time.Now()
+	if p.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.MaxElapsed)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !IsRetryable(err) {
+			return attempt, err
+		}
+
+		wait := p.backoff(attempt)
+		var rateLimited ErrRateLimited
+		if errors.As(err, &rateLimited) && rateLimited.RetryAfter > wait {
+			wait = rateLimited.RetryAfter
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryService wraps a lookup service and retries lookups according to a RetryPolicy
+type retryService struct {
+	next   LookupServiceInterface
+	policy RetryPolicy
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *retryService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for the calls to the lookup service.
+func (s *retryService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the lookup service holds for the given VAT number.
+func (s *retryService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the calls to the lookup service.
+func (s *retryService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ValidatorOpts,
+) (*ValidationResult, error) {
+	var r *ValidationResult
+	attempts, err := s.policy.do(ctx, func(ctx context.Context) error {
+		var err error
+		r, err = s.next.LookupContext(ctx, vatNumber, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Attempts = attempts
+	return r, nil
+}
@@ -0,0 +1,132 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Jitter:         0.5,
+}
+
+func TestRetryPolicyRetriesTemporaryFaults(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, viesFaultResponse, "MS_MAX_CONCURRENT_REQ")
+			return
+		}
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	defer server.Close()
+
+	c := NewClient(WithViesURL(server.URL), WithRetryPolicy(testRetryPolicy))
+	r, err := c.Lookup("BE0472429986")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.Attempts != 3 {
+		t.Errorf("Expected valid result after 3 attempts, got %+v", r)
+	}
+}
+
+func TestRetryPolicyGivesUp(t *testing.T) {
+	var faultTests = []struct {
+		fault         string
+		expectedCalls int32
+	}{
+		{"MS_UNAVAILABLE", 3},
+		{"IP_BLOCKED", 1},
+		{"INVALID_INPUT", 1},
+	}
+
+	for _, test := range faultTests {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, viesFaultResponse, test.fault)
+		}))
+
+		err := NewClient(WithViesURL(server.URL), WithRetryPolicy(testRetryPolicy)).ValidateExists("BE0472429986")
+		var fault *ViesFaultError
+		if !errors.As(err, &fault) || string(fault.Code) != test.fault {
+			t.Errorf("Expected %s fault, got <%v>", test.fault, err)
+		}
+		if calls != test.expectedCalls {
+			t.Errorf("Expected %d calls for %s, got %d", test.expectedCalls, test.fault, calls)
+		}
+		server.Close()
+	}
+}
+
+func TestRetryPolicyRespectsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL), WithRetryPolicy(testRetryPolicy))
+	opts := ValidatorOpts{UKAccessToken: &UKAccessToken{Token: "abc", ExpiresAt: time.Now().Add(time.Hour)}}
+
+	start := time.Now()
+	r, err := c.Lookup("GB553557819", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", r.Attempts)
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("Expected to wait at least 1s as asked by Retry-After, waited %s", time.Since(start))
+	}
+}
+
+func TestRetryPolicyMaxElapsed(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxElapsed: 100 * time.Millisecond}
+
+	attempts, err := p.do(context.Background(), func(ctx context.Context) error {
+		return ErrServiceUnavailable{Err: errors.New("down")}
+	})
+	if attempts != 1 || !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected to give up after 1 attempt, got %d attempts and <%v>", attempts, err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	var retryableTests = []struct {
+		err       error
+		retryable bool
+	}{
+		{ErrServiceUnavailable{Err: errors.New("connection refused")}, true},
+		{ErrServiceUnavailable{Err: context.Canceled}, false},
+		{ErrRateLimited{RetryAfter: time.Second}, true},
+		{ErrMemberStateUnavailable{Country: "DE"}, true},
+		{&ViesFaultError{Code: ViesIPBlocked}, false},
+		{ErrVATNumberNotFound, false},
+		{ErrInvalidVATNumberFormat, false},
+	}
+
+	for _, test := range retryableTests {
+		if IsRetryable(test.err) != test.retryable {
+			t.Errorf("Expected IsRetryable to be %v for <%v>", test.retryable, test.err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package vat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TokenStore persists UK access tokens, so that several processes can share one token instead of each generating
+// their own. Set it on a UKTokenSource with SetStore.
+type TokenStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load(ctx context.Context) (*UKAccessToken, error)
+	// Save stores the token, replacing the stored one.
+	Save(ctx context.Context, token *UKAccessToken) error
+	// Lock blocks until the caller holds the lock on the store or the context is done. The lock is held while a new
+	// token is generated, so that only one process generates it. It is released by calling unlock.
+	Lock(ctx context.Context) (unlock func(), err error)
+}
+
+// MemoryTokenStore is a TokenStore that keeps the token in memory, which shares it between the token sources of a
+// single process. It is safe for concurrent use.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *UKAccessToken
+	lock  chan struct{}
+}
+
+// NewMemoryTokenStore returns an empty in-memory token store.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{lock: make(chan struct{}, 1)}
+}
+
+// Load returns the stored token, or nil if there is none.
+func (s *MemoryTokenStore) Load(_ context.Context) (*UKAccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, nil
+	}
+	t := *s.token
+	return &t, nil
+}
+
+// Save stores a copy of the token.
+func (s *MemoryTokenStore) Save(_ context.Context, token *UKAccessToken) error {
+	t := *token
+	s.mu.Lock()
+	s.token = &t
+	s.mu.Unlock()
+	return nil
+}
+
+// Lock blocks until the caller holds the lock on the store or the context is done.
+func (s *MemoryTokenStore) Lock(ctx context.Context) (func(), error) {
+	select {
+	case s.lock <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-s.lock }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fileTokenStoreStaleLock is how old a lock file has to be before it is considered left behind by a process that
+// crashed while holding it
+const fileTokenStoreStaleLock = time.Minute
+
+// fileTokenStorePollInterval is how often Lock checks whether the lock file was removed
+const fileTokenStorePollInterval = 50 * time.Millisecond
+
+// FileTokenStore is a TokenStore that keeps the token in a JSON file, which shares it between processes with access to
+// the same file system, e.g. through a shared volume. The file is locked by creating a lock file next to it.
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore returns a token store that keeps the token in the file at path. The file is created on the first
+// Save, its directory has to exist.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load returns the token stored in the file, or nil if the file doesn't exist.
+func (s *FileTokenStore) Load(_ context.Context) (*UKAccessToken, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t UKAccessToken
+	if err = json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("vat: invalid token file %s: %w", s.path, err)
+	}
+	return &t, nil
+}
+
+// Save writes the token to the file. It is written to a temporary file first, so that readers never see a partially
+// written token. As the token grants access to the UK VAT API, the file is only readable by its owner.
+func (s *FileTokenStore) Save(_ context.Context, token *UKAccessToken) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if err = f.Chmod(0o600); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// Lock blocks until the caller created the lock file or the context is done. A lock file older than a minute is
+// removed, as the process that created it is assumed to have crashed.
+func (s *FileTokenStore) Lock(ctx context.Context) (func(), error) {
+	lockPath := s.path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					_ = os.Remove(lockPath)
+				})
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fileTokenStoreStaleLock {
+			_ = os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-time.After(fileTokenStorePollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testTokenStore(t *testing.T, store TokenStore) {
+	ctx := context.Background()
+
+	if token, err := store.Load(ctx); token != nil || err != nil {
+		t.Errorf("Expected no token in an empty store, got %+v <%v>", token, err)
+	}
+
+	saved := &UKAccessToken{Token: "abc", ExpiresAt: time.Now().Add(time.Hour).Round(0), IsTest: true}
+	if err := store.Save(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.Load(ctx)
+	if err != nil || token == nil || token.Token != "abc" || !token.IsTest || !token.ExpiresAt.Equal(saved.ExpiresAt) {
+		t.Errorf("Expected %+v, got %+v <%v>", saved, token, err)
+	}
+
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err = store.Lock(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the store to stay locked, got <%v>", err)
+	}
+	unlock()
+	unlock() // unlocking twice is harmless
+	unlock, err = store.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	testTokenStore(t, NewMemoryTokenStore())
+}
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uk-token.json")
+	testTokenStore(t, NewFileTokenStore(path))
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the token file to be only readable by its owner, got %v <%v>", info, err)
+	}
+
+	// a lock left behind by a crashed process is taken over
+	store := NewFileTokenStore(path)
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * fileTokenStoreStaleLock)
+	if err := os.Chtimes(path+".lock", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got <%v>", err)
+	}
+	unlock()
+}
+
+func TestUKTokenSourceStore(t *testing.T) {
+	var calls int32
+	server := newUKTokenTestServer(14400, &calls)
+	defer server.Close()
+
+	c := NewClient(WithUKURL(server.URL))
+	opts := ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"}
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "uk-token.json"))
+
+	// token sources of different processes share the token through the store
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts := NewUKTokenSource(c, opts)
+			ts.SetStore(store)
+			if token, err := ts.Token(context.Background()); err != nil || token.Token != "token-1" {
+				t.Errorf("Expected token-1, got %+v <%v>", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call to generate a token, got %d", atomic.LoadInt32(&calls))
+	}
+
+	// tokens for the test API aren't used for the production API
+	if err := store.Save(context.Background(), &UKAccessToken{
+		Token:     "test",
+		ExpiresAt: time.Now().Add(time.Hour),
+		IsTest:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ts := NewUKTokenSource(c, opts)
+	ts.SetStore(store)
+	if token, err := ts.Token(context.Background()); err != nil || token.Token != "token-2" {
+		t.Errorf("Expected token-2, got %+v <%v>", token, err)
+	}
+}
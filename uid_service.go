@@ -0,0 +1,172 @@
+package vat
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CHUIDLookupService validates Swiss VAT numbers with the public services of the federal UID register.
+var CHUIDLookupService LookupServiceInterface = &uidService{}
+
+// uidService validates Swiss VAT numbers with the UID register
+type uidService struct {
+	client *Client
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *uidService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for the calls to the UID register.
+func (s *uidService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the UID register holds for the given VAT number.
+// A number that is not registered for VAT is reported through ValidationResult.Valid rather than an error.
+func (s *uidService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the calls to the UID register.
+// The register is asked whether the number is an active VAT registration and, if it is, for the company's details.
+func (s *uidService) LookupContext(ctx context.Context, vatNumber string, _ ValidatorOpts) (*ValidationResult, error) {
+	n := normalizeVATNumber(vatNumber)
+	if !strings.HasPrefix(n, "CHE") || len(n) != 12 || !isDigits(n[3:]) {
+		return nil, ErrInvalidVATNumberFormat
+	}
+
+	result := &ValidationResult{
+		CountryCode: "CH",
+		VATNumber:   n[2:],
+		RequestDate: time.Now(),
+		Service:     ServiceUID,
+		Attempts:    1,
+	}
+
+	var valid struct {
+		Result bool `xml:"Body>ValidateVatNumberResponse>ValidateVatNumberResult"`
+	}
+	body := "<uid:vatNumber>CHE-" + n[3:6] + "." + n[6:9] + "." + n[9:] + "</uid:vatNumber>"
+	if err := s.call(ctx, "ValidateVatNumber", body, &valid); err != nil {
+		return nil, err
+	}
+	if !valid.Result {
+		return result, nil
+	}
+	result.Valid = true
+
+	var org struct {
+		Organisation struct {
+			Name    string `xml:"organisationIdentification>organisationName"`
+			Address struct {
+				Street      string `xml:"street"`
+				HouseNumber string `xml:"houseNumber"`
+				ZipCode     string `xml:"swissZipCode"`
+				Town        string `xml:"town"`
+			} `xml:"address"`
+		} `xml:"Body>GetByUIDResponse>GetByUIDResult>organisationType>organisation"`
+	}
+	body = `<uid:uid>
+		<uidOrganisationIdCategorie xmlns="http://www.ech.ch/xmlns/eCH-0097/5">CHE</uidOrganisationIdCategorie>
+		<uidOrganisationId xmlns="http://www.ech.ch/xmlns/eCH-0097/5">` + n[3:] + `</uidOrganisationId>
+	</uid:uid>`
+	if err := s.call(ctx, "GetByUID", body, &org); err != nil {
+		return nil, err
+	}
+
+	o := org.Organisation
+	result.Name = strings.TrimSpace(o.Name)
+	street := strings.TrimSpace(o.Address.Street + " " + o.Address.HouseNumber)
+	town := strings.TrimSpace(o.Address.ZipCode + " " + o.Address.Town)
+	result.Address = strings.TrimSpace(street + "\n" + town)
+	return result, nil
+}
+
+// call calls an operation of the UID register and decodes the response envelope into v,
+// or returns an error if the register reported one
+func (s *uidService) call(ctx context.Context, operation, body string, v interface{}) error {
+	c := s.getClient()
+	envelope := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`xmlns:uid="http://www.uid.admin.ch/xmlns/uid-wse">
+<soapenv:Header/>
+<soapenv:Body>
+	<uid:` + operation + `>
+		` + body + `
+	</uid:` + operation + `>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.getUIDURL(), bytes.NewBufferString(envelope))
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	req.Header.Set("Content-Type", "text/xml;charset=UTF-8")
+	req.Header.Set("SOAPAction", `"http://www.uid.admin.ch/xmlns/uid-wse/IPublicServices/`+operation+`"`)
+
+	res, err := c.do(req)
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	xmlRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+
+	var fault struct {
+		Fault *struct {
+			String string `xml:"faultstring"`
+		} `xml:"Body>Fault"`
+	}
+	if err = xml.Unmarshal(xmlRes, &fault); err != nil {
+		if res.StatusCode != http.StatusOK {
+			return ErrServiceUnavailable{Err: fmt.Errorf("unexpected status code from UID register: %d", res.StatusCode)}
+		}
+		return ErrServiceUnavailable{Err: err}
+	}
+	if f := fault.Fault; f != nil {
+		return uidFaultError(strings.TrimSpace(f.String))
+	}
+	if res.StatusCode != http.StatusOK {
+		return ErrServiceUnavailable{Err: fmt.Errorf("unexpected status code from UID register: %d", res.StatusCode)}
+	}
+
+	if err = xml.Unmarshal(xmlRes, v); err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	return nil
+}
+
+// uidFaultError maps a fault returned by the UID register to an error. The register allows 20 requests per minute
+// for each client.
+func uidFaultError(fault string) error {
+	switch {
+	case strings.Contains(fault, "Request_limit_exceeded"):
+		return ErrRateLimited{RetryAfter: time.Minute, Err: errors.New(fault)}
+	case strings.Contains(fault, "Data_validation_failed"):
+		return ErrInvalidVATNumberFormat
+	default:
+		return ErrServiceUnavailable{Err: fmt.Errorf("UID register fault: %s", fault)}
+	}
+}
+
+func (s *uidService) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	return defaultClient
+}
+
+const uidServiceURL = "https://www.uid-wse.admin.ch/V5.0/PublicServices.svc"
@@ -0,0 +1,103 @@
+package vat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const uidValidateResponse = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+<s:Body>
+	<ValidateVatNumberResponse xmlns="http://www.uid.admin.ch/xmlns/uid-wse">
+		<ValidateVatNumberResult>%s</ValidateVatNumberResult>
+	</ValidateVatNumberResponse>
+</s:Body>
+</s:Envelope>`
+
+const uidGetByUIDResponse = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+<s:Body>
+	<GetByUIDResponse xmlns="http://www.uid.admin.ch/xmlns/uid-wse">
+		<GetByUIDResult>
+			<organisationType>
+				<organisation xmlns="http://www.uid.admin.ch/xmlns/uid-wse-shared/2">
+					<organisationIdentification xmlns="http://www.ech.ch/xmlns/eCH-0108/5">
+						<organisationName>ACME AG</organisationName>
+					</organisationIdentification>
+					<address xmlns="http://www.ech.ch/xmlns/eCH-0108/5">
+						<street>Bahnhofstrasse</street>
+						<houseNumber>1</houseNumber>
+						<town>Zürich</town>
+						<swissZipCode>8001</swissZipCode>
+					</address>
+				</organisation>
+			</organisationType>
+		</GetByUIDResult>
+	</GetByUIDResponse>
+</s:Body>
+</s:Envelope>`
+
+const uidFaultResponse = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+<s:Body>
+	<s:Fault>
+		<faultcode>s:Client</faultcode>
+		<faultstring>%s</faultstring>
+	</s:Fault>
+</s:Body>
+</s:Envelope>`
+
+func newUIDTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body := string(b)
+		action := r.Header.Get("SOAPAction")
+
+		switch {
+		case strings.Contains(body, "113690319") || strings.Contains(body, "113.690.319"):
+			if strings.HasSuffix(action, `/ValidateVatNumber"`) {
+				_, _ = io.WriteString(w, strings.Replace(uidValidateResponse, "%s", "true", 1))
+			} else if strings.HasSuffix(action, `/GetByUID"`) {
+				_, _ = io.WriteString(w, uidGetByUIDResponse)
+			} else {
+				t.Errorf("Unexpected SOAPAction %s", action)
+			}
+		case strings.Contains(body, "100.155.212"):
+			_, _ = io.WriteString(w, strings.Replace(uidValidateResponse, "%s", "false", 1))
+		case strings.Contains(body, "109.322.551"):
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, strings.Replace(uidFaultResponse, "%s", "Request_limit_exceeded", 1))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+}
+
+func TestUIDService(t *testing.T) {
+	server := newUIDTestServer(t)
+	defer server.Close()
+
+	c := NewClient(WithUIDURL(server.URL))
+
+	r, err := c.Lookup("CHE-113.690.319 MWST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.CountryCode != "CH" || r.VATNumber != "E113690319" || r.Service != ServiceUID {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.Name != "ACME AG" || r.Address != "Bahnhofstrasse 1\n8001 Zürich" {
+		t.Errorf("Unexpected name and address: %q %q", r.Name, r.Address)
+	}
+
+	if err = c.Validate("CHE100155212"); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v>, got <%v>", ErrVATNumberNotFound, err)
+	}
+
+	err = c.ValidateExists("CHE109322551")
+	var rateLimited ErrRateLimited
+	if !errors.As(err, &rateLimited) || !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected <%v>, got <%v>", ErrRateLimited{}, err)
+	}
+}
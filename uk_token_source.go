@@ -0,0 +1,177 @@
+package vat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ukTokenRefreshAhead is how long before it expires a cached UK access token is refreshed
+const ukTokenRefreshAhead = 5 * time.Minute
+
+// UKTokenSource generates access tokens for the UK VAT API from client credentials and caches them until shortly
+// before they expire. Concurrent lookups that need a new token share a single call to the UK VAT API.
+// Pass it to a client with WithUKTokenSource, or to a single call in ValidatorOpts.UKTokenSource.
+// It is safe for concurrent use.
+type UKTokenSource struct {
+	client *Client
+	opts   ValidatorOpts
+
+	mu      sync.Mutex
+	store   TokenStore
+	token   *UKAccessToken
+	pending *ukTokenCall
+	// rejected is the last token the UK VAT API rejected, which is not used again even if the store still holds it
+	rejected string
+}
+
+// ukTokenCall is a call to generate a token that other callers can wait for
+type ukTokenCall struct {
+	done  chan struct{}
+	token *UKAccessToken
+	err   error
+	// cancelled is set if the context of the caller that made the call was done before the call returned
+	cancelled bool
+}
+
+// NewUKTokenSource returns a token source that generates tokens with the UKClientID, UKClientSecret and IsUKTest
+// set in opts. If client is nil, tokens are generated with the client the token source is used with, or with the
+// default client when Token is called directly.
+func NewUKTokenSource(client *Client, opts ValidatorOpts) *UKTokenSource {
+	return &UKTokenSource{
+		client: client,
+		opts: ValidatorOpts{
+			UKClientID:     opts.UKClientID,
+			UKClientSecret: opts.UKClientSecret,
+			IsUKTest:       opts.IsUKTest,
+		},
+	}
+}
+
+// SetStore sets the store the token is shared through. Before a token is generated, the stored token is used if it
+// doesn't expire within the next few minutes; generated tokens are saved to the store.
+func (s *UKTokenSource) SetStore(store TokenStore) {
+	s.mu.Lock()
+	s.store = store
+	s.mu.Unlock()
+}
+
+// Token returns the cached access token, or generates a new one if it expires within the next few minutes.
+func (s *UKTokenSource) Token(ctx context.Context) (*UKAccessToken, error) {
+	return s.get(ctx, defaultClient)
+}
+
+// invalidate drops the token after the UK VAT API rejected it, so that the next call generates a new one
+func (s *UKTokenSource) invalidate(token *UKAccessToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected = token.Token
+	if s.token != nil && s.token.Token == token.Token {
+		s.token = nil
+	}
+}
+
+// get is like Token, but tokens are generated with c unless the token source has its own client
+func (s *UKTokenSource) get(ctx context.Context, c *Client) (*UKAccessToken, error) {
+	if s.client != nil {
+		c = s.client
+	}
+
+	for {
+		s.mu.Lock()
+		current := s.token
+		if current != nil && time.Until(current.ExpiresAt) > ukTokenRefreshAhead {
+			s.mu.Unlock()
+			return current, nil
+		}
+
+		if call := s.pending; call != nil {
+			s.mu.Unlock()
+			if current != nil && !current.IsExpired() {
+				// another caller is already refreshing the token, which can still be used in the meantime
+				return current, nil
+			}
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ErrUnableToGenerateUKAccessToken{Err: ctx.Err()}
+			}
+			if call.cancelled && ctx.Err() == nil {
+				// the call was cancelled by the caller that made it, so try again with our own context
+				continue
+			}
+			return call.token, call.err
+		}
+
+		call := &ukTokenCall{done: make(chan struct{})}
+		s.pending = call
+		store, rejected := s.store, s.rejected
+		s.mu.Unlock()
+
+		call.token, call.err = s.generate(ctx, c, store, rejected)
+		call.cancelled = call.err != nil && ctx.Err() != nil
+
+		s.mu.Lock()
+		if call.err == nil {
+			s.token = call.token
+		}
+		s.pending = nil
+		s.mu.Unlock()
+		close(call.done)
+
+		if call.err != nil && current != nil && !current.IsExpired() {
+			// keep using the current token until it expires, the next call will try to refresh it again
+			return current, nil
+		}
+		return call.token, call.err
+	}
+}
+
+// generate returns a token from the store if it holds a fresh one, or else generates a new one and saves it to the
+// store. The store is locked while the token is generated, so that only one process generates it.
+func (s *UKTokenSource) generate(
+	ctx context.Context,
+	c *Client,
+	store TokenStore,
+	rejected string,
+) (*UKAccessToken, error) {
+	if store == nil {
+		return c.GenerateUKAccessTokenContext(ctx, s.opts)
+	}
+	if t := s.loadFresh(ctx, store, rejected); t != nil {
+		return t, nil
+	}
+
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		return nil, ErrUnableToGenerateUKAccessToken{Err: fmt.Errorf("unable to lock token store: %w", err)}
+	}
+	defer unlock()
+
+	// another process may have saved a token while we were waiting for the lock
+	if t := s.loadFresh(ctx, store, rejected); t != nil {
+		return t, nil
+	}
+
+	t, err := c.GenerateUKAccessTokenContext(ctx, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	// the token can be used even if it couldn't be saved, other processes will generate their own
+	_ = store.Save(ctx, t)
+	return t, nil
+}
+
+// loadFresh returns the stored token if it was generated for the same API, doesn't expire within the refresh window and
+// wasn't rejected. A token that can't be loaded is treated as missing.
+func (s *UKTokenSource) loadFresh(ctx context.Context, store TokenStore, rejected string) *UKAccessToken {
+	t, err := store.Load(ctx)
+	if err != nil || t == nil || t.Token == "" || t.Token == rejected || t.IsTest != s.opts.IsUKTest {
+		return nil
+	}
+	if time.Until(t.ExpiresAt) <= ukTokenRefreshAhead {
+		return nil
+	}
+	return t
+}
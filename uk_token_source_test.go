@@ -0,0 +1,96 @@
+package vat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newUKTokenTestServer(expiresIn int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			n := atomic.AddInt32(calls, 1)
+			// give concurrent callers time to pile up
+			time.Sleep(20 * time.Millisecond)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%d}`, n, expiresIn)
+		case "/organisations/vat/check-vat-number/lookup/553557819":
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestUKTokenSourceSingleFlight(t *testing.T) {
+	var calls int32
+	server := newUKTokenTestServer(14400, &calls)
+	defer server.Close()
+
+	ts := NewUKTokenSource(NewClient(WithUKURL(server.URL)), ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"})
+
+	var wg sync.WaitGroup
+	tokens := make([]*UKAccessToken, 10)
+	errs := make([]error, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = ts.Token(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] == nil || tokens[i].Token != "token-1" {
+			t.Errorf("Expected token-1, got %+v <%v>", tokens[i], errs[i])
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call to generate a token, got %d", calls)
+	}
+
+	// the cached token is returned until it is about to expire
+	token, err := ts.Token(context.Background())
+	if err != nil || token.Token != "token-1" || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected the cached token, got %+v <%v> after %d calls", token, err, atomic.LoadInt32(&calls))
+	}
+}
+
+func TestUKTokenSourceRefreshesAhead(t *testing.T) {
+	var calls int32
+	// the token expires within the refresh window right away
+	server := newUKTokenTestServer(int((ukTokenRefreshAhead - time.Minute).Seconds()), &calls)
+	defer server.Close()
+
+	ts := NewUKTokenSource(NewClient(WithUKURL(server.URL)), ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"})
+	for _, expected := range []string{"token-1", "token-2"} {
+		if token, err := ts.Token(context.Background()); err != nil || token.Token != expected {
+			t.Errorf("Expected %s, got %+v <%v>", expected, token, err)
+		}
+	}
+}
+
+func TestClientUKTokenSource(t *testing.T) {
+	var calls int32
+	server := newUKTokenTestServer(14400, &calls)
+	defer server.Close()
+
+	ts := NewUKTokenSource(nil, ValidatorOpts{UKClientID: "id", UKClientSecret: "secret"})
+	c := NewClient(WithUKURL(server.URL), WithUKTokenSource(ts))
+
+	for i := 0; i < 3; i++ {
+		if err := c.Validate("GB553557819"); err != nil {
+			t.Errorf("Expected <nil>, got <%v>", err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call to generate a token, got %d", calls)
+	}
+}
@@ -2,86 +2,265 @@ package vat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // ukVATService is service that calls a UK VAT API to validate UK VAT numbers.
-type ukVATService struct{}
+type ukVATService struct {
+	client *Client
+}
 
 // Validate checks if the given VAT number exists and is active. If no error is returned, then it is.
 func (s *ukVATService) Validate(vatNumber string, opts ValidatorOpts) error {
-	if opts.UKAccessToken == nil || opts.UKAccessToken.IsExpired() {
-		// if no access token is provided or if it's expired, try to generate one
-		// (it is recommended to generate one separately and cache it and pass it in as an option here)
-		accessToken, err := GenerateUKAccessToken(opts)
-		if err != nil {
-			return ErrMissingUKAccessToken
-		}
-		opts.UKAccessToken = accessToken
-	}
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
 
+// ValidateContext is like Validate, but the context is used for the calls to the UK VAT API.
+func (s *ukVATService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the UK VAT API holds for the given VAT number.
+// A number that is not registered is reported through ValidationResult.Valid rather than an error.
+func (s *ukVATService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the calls to the UK VAT API.
+func (s *ukVATService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ValidatorOpts,
+) (*ValidationResult, error) {
 	vatNumber = strings.ToUpper(vatNumber)
 
 	// Only VAT numbers starting with "GB" are supported by this service. All others should go through the VIES service.
 	if !strings.HasPrefix(vatNumber, "GB") {
-		return ErrInvalidCountryCode
+		return nil, ErrInvalidCountryCode
 	}
 
+	gb := parseGBVATNumber(vatNumber[2:])
+	if !gb.Kind.CanLookup() {
+		// the UK VAT API doesn't know about government departments and health authorities,
+		// so they're valid as long as their format is
+		if !gbNonLookupPattern.MatchString(gb.Number) {
+			return nil, ErrInvalidVATNumberFormat
+		}
+		return &ValidationResult{
+			CountryCode: "GB",
+			VATNumber:   gb.Number,
+			Valid:       true,
+			RequestDate: time.Now(),
+		}, nil
+	}
+
+	// branch traders are registered under the first 9 digits of their number
 	apiURL := fmt.Sprintf(
 		"%s/organisations/vat/check-vat-number/lookup/%s",
-		ukVatServiceURL(opts.IsUKTest),
-		vatNumber[2:],
+		s.getClient().getUKURL(opts.IsUKTest),
+		gb.Number,
 	)
+	if opts.UKRequesterVRN != "" {
+		// HMRC only returns a consultation number if the requester's own VRN is part of the check
+		requester, err := ukRequesterVRN(opts.UKRequesterVRN, opts)
+		if err != nil {
+			return nil, err
+		}
+		apiURL += "/" + requester
+	}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return ErrServiceUnavailable{Err: err}
+	token := opts.UKAccessToken
+	var rejected *UKAccessToken
+	for {
+		if token == nil || token.IsExpired() || rejected != nil {
+			// if no access token is provided or if it's expired, get one from the token source or try to generate one
+			// (it is recommended to use a UKTokenSource, which caches the token, rather than generating one every time)
+			var err error
+			if token, err = s.accessToken(ctx, opts, rejected); err != nil {
+				return nil, missingUKAccessTokenError{Err: err}
+			}
+		}
+
+		result, err := s.lookup(ctx, apiURL, token, gb)
+		var apiErr *UKAPIError
+		if rejected == nil && errors.As(err, &apiErr) && apiErr.Unauthorized() && s.canGenerateToken(opts) {
+			// the token was revoked or expired early, so get a new one and try once more
+			rejected = token
+			continue
+		}
+		return result, err
 	}
+}
 
-	req.Header.Set("Accept", "application/vnd.hmrc.2.0+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", opts.UKAccessToken.Token))
+// accessToken returns a token from the token source, or else generates one from the client credentials in opts.
+// A token that was rejected by the UK VAT API is dropped from the token source first.
+func (s *ukVATService) accessToken(
+	ctx context.Context,
+	opts ValidatorOpts,
+	rejected *UKAccessToken,
+) (*UKAccessToken, error) {
+	if ts := s.getTokenSource(opts); ts != nil {
+		if rejected != nil {
+			ts.invalidate(rejected)
+		}
+		return ts.get(ctx, s.getClient())
+	}
+	return s.getClient().GenerateUKAccessTokenContext(ctx, opts)
+}
 
-	client := &http.Client{
-		Timeout: serviceTimeout,
+// canGenerateToken returns whether a new token can be obtained when the one used was rejected
+func (s *ukVATService) canGenerateToken(opts ValidatorOpts) bool {
+	return s.getTokenSource(opts) != nil || (opts.UKClientID != "" && opts.UKClientSecret != "")
+}
+
+// lookup calls the UK VAT API to look up the GB number with the given token
+func (s *ukVATService) lookup(
+	ctx context.Context,
+	apiURL string,
+	token *UKAccessToken,
+	gb *GBVATNumber,
+) (*ValidationResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
 	}
 
-	response, err := client.Do(req)
+	req.Header.Set("Accept", "application/vnd.hmrc.2.0+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.Token))
+
+	response, err := s.getClient().do(req)
 	if err != nil {
-		return ErrServiceUnavailable{Err: err}
+		return nil, ErrServiceUnavailable{Err: err}
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(response.Body)
 
-	if response.StatusCode == http.StatusBadRequest {
-		return ErrInvalidVATNumberFormat
+	result := &ValidationResult{
+		CountryCode: "GB",
+		VATNumber:   gb.Number,
+		Service:     ServiceHMRC,
+		Attempts:    1,
 	}
-	if response.StatusCode == http.StatusNotFound {
-		return ErrVATNumberNotFound
-	}
-	if response.StatusCode != http.StatusOK {
-		return ErrServiceUnavailable{
-			Err: fmt.Errorf("unexpected status code from UK VAT API: %d", response.StatusCode),
+	switch {
+	case response.StatusCode == http.StatusOK:
+	case response.StatusCode == http.StatusNotFound:
+		return result, nil
+	case response.StatusCode == http.StatusTooManyRequests:
+		return nil, ErrRateLimited{
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+			Err:        parseUKAPIError(response),
 		}
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		// other client errors won't go away by making the same request again
+		return nil, parseUKAPIError(response)
+	default:
+		return nil, ErrServiceUnavailable{Err: parseUKAPIError(response)}
+	}
+
+	var rd ukLookupResponse
+	if err = json.NewDecoder(response.Body).Decode(&rd); err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
 	}
 
 	// If we receive a valid 200 response from this API, it means the VAT number exists and is valid
-	return nil
+	result.Valid = true
+	result.Name = strings.TrimSpace(rd.Target.Name)
+	result.Address = rd.Target.Address.String()
+	result.RequestDate = rd.ProcessingDate
+	if result.RequestDate.IsZero() {
+		result.RequestDate = time.Now()
+	}
+	result.ConsultationNumber = rd.ConsultationNumber
+	return result, nil
+}
+
+// ukRequesterVRN validates the requester VRN passed in ValidatorOpts.UKRequesterVRN, which may be given with or
+// without its GB country code, and returns it without the country code.
+func ukRequesterVRN(vrn string, opts ValidatorOpts) (string, error) {
+	n := normalizeVATNumber(vrn)
+	if isDigits(n) {
+		n = "GB" + n
+	}
+	if !strings.HasPrefix(n, "GB") {
+		return "", ukRequesterVRNError{Err: ErrInvalidCountryCode}
+	}
+	if err := ValidateFormat(n, opts); err != nil {
+		return "", ukRequesterVRNError{Err: err}
+	}
+	gb := parseGBVATNumber(n[2:])
+	if !gb.Kind.CanLookup() {
+		return "", ukRequesterVRNError{Err: ErrInvalidVATNumberFormat}
+	}
+	return gb.Number, nil
+}
+
+// ukLookupResponse is the response of version 2.0 of the check-vat-number lookup endpoint
+type ukLookupResponse struct {
+	Target struct {
+		Name      string    `json:"name"`
+		VATNumber string    `json:"vatNumber"`
+		Address   ukAddress `json:"address"`
+	} `json:"target"`
+	// Requester is only set for lookups made with a requester VRN, and so is ConsultationNumber
+	Requester          string    `json:"requester"`
+	ConsultationNumber string    `json:"consultationNumber"`
+	ProcessingDate     time.Time `json:"processingDate"`
+}
+
+// ukAddress is an address returned by the UK VAT API
+type ukAddress struct {
+	Line1       string `json:"line1"`
+	Line2       string `json:"line2"`
+	Line3       string `json:"line3"`
+	Line4       string `json:"line4"`
+	Line5       string `json:"line5"`
+	Postcode    string `json:"postcode"`
+	CountryCode string `json:"countryCode"`
+}
+
+// String returns the address lines and postcode separated by newlines, like the addresses returned by VIES
+func (a ukAddress) String() string {
+	var lines []string
+	for _, l := range []string{a.Line1, a.Line2, a.Line3, a.Line4, a.Line5, a.Postcode} {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *ukVATService) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	return defaultClient
+}
+
+// getTokenSource returns the token source passed in opts, or else the one the client was created with
+func (s *ukVATService) getTokenSource(opts ValidatorOpts) *UKTokenSource {
+	if opts.UKTokenSource != nil {
+		return opts.UKTokenSource
+	}
+	return s.getClient().ukTokenSource
 }
 
 // UKAccessToken contains access token information used to authenticate with the UK VAT API.
+// It can be stored as JSON, which is what FileTokenStore does.
 type UKAccessToken struct {
 	Token               string    `json:"access_token"`
 	SecondsUntilExpires int64     `json:"expires_in"`
 	ExpiresAt           time.Time `json:"expires_at"`
-	IsTest              bool      `json:"-"`
+	IsTest              bool      `json:"is_test,omitempty"`
 }
 
 // IsExpired checks if the access token is expired.
@@ -91,6 +270,21 @@ func (t UKAccessToken) IsExpired() bool {
 
 // GenerateUKAccessToken generates an access token from given client credentials for use with the UK VAT API.
 func GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
+	return GenerateUKAccessTokenContext(context.Background(), opts)
+}
+
+// GenerateUKAccessTokenContext is like GenerateUKAccessToken, but the context is used for the call to the UK VAT API.
+func GenerateUKAccessTokenContext(ctx context.Context, opts ValidatorOpts) (*UKAccessToken, error) {
+	return defaultClient.GenerateUKAccessTokenContext(ctx, opts)
+}
+
+// GenerateUKAccessToken generates an access token from given client credentials for use with the UK VAT API.
+func (c *Client) GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
+	return c.GenerateUKAccessTokenContext(context.Background(), opts)
+}
+
+// GenerateUKAccessTokenContext is like GenerateUKAccessToken, but the context is used for the call to the UK VAT API.
+func (c *Client) GenerateUKAccessTokenContext(ctx context.Context, opts ValidatorOpts) (*UKAccessToken, error) {
 	if opts.UKClientID == "" || opts.UKClientSecret == "" {
 		return nil, ErrUnableToGenerateUKAccessToken{Err: errors.New("missing client ID or secret")}
 	}
@@ -101,9 +295,10 @@ func GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
 	data.Set("grant_type", "client_credentials")
 	data.Set("scope", "read:vat")
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		fmt.Sprintf("%s/oauth/token", ukVatServiceURL(opts.IsUKTest)),
+		fmt.Sprintf("%s/oauth/token", c.getUKURL(opts.IsUKTest)),
 		bytes.NewBufferString(data.Encode()),
 	)
 	if err != nil {
@@ -112,8 +307,7 @@ func GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, ErrUnableToGenerateUKAccessToken{Err: err}
 	}
@@ -122,13 +316,7 @@ func GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, ErrUnableToGenerateUKAccessToken{Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
-		}
-		return nil, ErrUnableToGenerateUKAccessToken{
-			Err: fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, respBody),
-		}
+		return nil, ErrUnableToGenerateUKAccessToken{Err: parseUKAPIError(resp)}
 	}
 
 	var token UKAccessToken
@@ -144,6 +332,49 @@ func GenerateUKAccessToken(opts ValidatorOpts) (*UKAccessToken, error) {
 	return &token, nil
 }
 
+// parseUKAPIError decodes the error body of a response from the UK VAT API. The API returns a code and message, while
+// its OAuth endpoint returns an error and error description. Bodies in neither format are kept as the message.
+func parseUKAPIError(res *http.Response) *UKAPIError {
+	apiErr := &UKAPIError{StatusCode: res.StatusCode}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var rd struct {
+		Code             string `json:"code"`
+		Message          string `json:"message"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.Unmarshal(body, &rd); err != nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+	apiErr.Code, apiErr.Message = rd.Code, rd.Message
+	if apiErr.Code == "" {
+		apiErr.Code, apiErr.Message = rd.Error, rd.ErrorDescription
+	}
+	return apiErr
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which holds either seconds or an HTTP date.
+// Zero is returned if the header is missing or can't be parsed.
+func parseRetryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(h); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 func ukVatServiceURL(isTest bool) string {
 	if isTest {
 		return fmt.Sprintf("https://test-%s", ukVATServiceDomain)
@@ -1,8 +1,7 @@
 package vat
 
 import (
-	"fmt"
-	"regexp"
+	"context"
 	"strings"
 )
 
@@ -11,91 +10,229 @@ import (
 // If no opts are passed in, VIES numbers will still be validated as always, but GB numbers will not.
 // If multiple opts arguments passed in, only the first one is used.
 func Validate(vatNumber string, opts ...ValidatorOpts) error {
-	err := ValidateFormat(vatNumber)
+	return defaultClient.Validate(vatNumber, opts...)
+}
+
+// ValidateContext is like Validate, but the context is used for the call to the external lookup service.
+func ValidateContext(ctx context.Context, vatNumber string, opts ...ValidatorOpts) error {
+	return defaultClient.ValidateContext(ctx, vatNumber, opts...)
+}
+
+// ValidateFormat validates a VAT number by its format and, for countries that use them, its check digits.
+// The number is normalized first, see Normalize, and validated against the rule registered for its country,
+// see RegisterCountry. If no error is returned then it is valid.
+// Like Validate, passing in opts is optional; set ValidatorOpts.SkipChecksum to only validate the format.
+func ValidateFormat(vatNumber string, optsSlice ...ValidatorOpts) error {
+	vatNumber = normalizeVATNumber(vatNumber)
+	if len(vatNumber) < 3 {
+		return ErrInvalidVATNumberFormat
+	}
+
+	rule, ok := countryRuleFor(vatNumber[0:2])
+	if !ok {
+		return ErrInvalidCountryCode
+	}
+	if !rule.pattern.MatchString(vatNumber[2:]) {
+		return ErrInvalidVATNumberFormat
+	}
+
+	if rule.Checksum != nil && !firstOpts(optsSlice).SkipChecksum && !rule.Checksum(vatNumber[2:]) {
+		return ErrInvalidVATNumberChecksum
+	}
+	return nil
+}
+
+// ValidateExists validates that the given VAT number exists in the external lookup service.
+func ValidateExists(vatNumber string, optsSlice ...ValidatorOpts) error {
+	return defaultClient.ValidateExists(vatNumber, optsSlice...)
+}
+
+// ValidateExistsContext is like ValidateExists, but the context is used for the call to the external lookup service.
+func ValidateExistsContext(ctx context.Context, vatNumber string, optsSlice ...ValidatorOpts) error {
+	return defaultClient.ValidateExistsContext(ctx, vatNumber, optsSlice...)
+}
+
+// Lookup validates a VAT number by its format and returns the details the external lookup service holds for it.
+// Like Validate, passing in opts is optional and only the first one is used.
+// A number that passes the format check but is not registered is reported through ValidationResult.Valid.
+func Lookup(vatNumber string, opts ...ValidatorOpts) (*ValidationResult, error) {
+	return defaultClient.Lookup(vatNumber, opts...)
+}
+
+// LookupContext is like Lookup, but the context is used for the call to the external lookup service.
+func LookupContext(ctx context.Context, vatNumber string, opts ...ValidatorOpts) (*ValidationResult, error) {
+	return defaultClient.LookupContext(ctx, vatNumber, opts...)
+}
+
+// LookupExists returns the details the external lookup service holds for the given VAT number,
+// without validating its format first.
+func LookupExists(vatNumber string, optsSlice ...ValidatorOpts) (*ValidationResult, error) {
+	return defaultClient.LookupExists(vatNumber, optsSlice...)
+}
+
+// LookupExistsContext is like LookupExists, but the context is used for the call to the external lookup service.
+func LookupExistsContext(
+	ctx context.Context,
+	vatNumber string,
+	optsSlice ...ValidatorOpts,
+) (*ValidationResult, error) {
+	return defaultClient.LookupExistsContext(ctx, vatNumber, optsSlice...)
+}
+
+// Validate validates a VAT number by both format and existence. If no error then it is valid.
+func (c *Client) Validate(vatNumber string, opts ...ValidatorOpts) error {
+	err := ValidateFormat(vatNumber, opts...)
 	if err != nil {
 		return err
 	}
-	return ValidateExists(vatNumber, opts...)
-}
-
-// ValidateFormat validates a VAT number by its format. If no error is returned then it is valid.
-func ValidateFormat(vatNumber string) error {
-	patterns := map[string]string{
-		"AT": `U[A-Z0-9]{8}`,
-		"BE": `(0[0-9]{9}|[0-9]{10})`,
-		"BG": `[0-9]{9,10}`,
-		"CH": `(?:E(?:-| )[0-9]{3}(?:\.| )[0-9]{3}(?:\.| )[0-9]{3}( MWST)?|E[0-9]{9}(?:MWST)?)`,
-		"CY": `[0-9]{8}[A-Z]`,
-		"CZ": `[0-9]{8,10}`,
-		"DE": `[0-9]{9}`,
-		"DK": `[0-9]{8}`,
-		"EE": `[0-9]{9}`,
-		"EL": `[0-9]{9}`,
-		"ES": `[A-Z][0-9]{7}[A-Z]|[0-9]{8}[A-Z]|[A-Z][0-9]{8}`,
-		"FI": `[0-9]{8}`,
-		"FR": `[A-Z0-9]{2}[0-9]{9}`,
-		// Supposedly the regex for GB numbers is `[0-9]{9}|[0-9]{12}|(GD|HA)[0-9]{3}`,
-		// but our validator service only accepts numbers with 9 or 12 digits following the country code.
-		// Seems like the official site only accepts 9 digits... https://www.gov.uk/check-uk-vat-number
-		"GB": `([0-9]{9}|[0-9]{12})`,
-		"HR": `[0-9]{11}`,
-		"HU": `[0-9]{8}`,
-		"IE": `[A-Z0-9]{7}[A-Z]|[A-Z0-9]{7}[A-W][A-I]`,
-		"IT": `[0-9]{11}`,
-		"LT": `([0-9]{9}|[0-9]{12})`,
-		"LU": `[0-9]{8}`,
-		"LV": `[0-9]{11}`,
-		"MT": `[0-9]{8}`,
-		"NL": `[0-9]{9}B[0-9]{2}`,
-		"PL": `[0-9]{10}`,
-		"PT": `[0-9]{9}`,
-		"RO": `[0-9]{2,10}`,
-		"SE": `[0-9]{12}`,
-		"SI": `[0-9]{8}`,
-		"SK": `[0-9]{10}`,
-		"XI": `([0-9]{9}|[0-9]{12})`, // Northern Ireland, same format as GB
+	return c.ValidateExists(vatNumber, opts...)
+}
+
+// ValidateContext is like Validate, but the context is used for the call to the external lookup service.
+func (c *Client) ValidateContext(ctx context.Context, vatNumber string, opts ...ValidatorOpts) error {
+	err := ValidateFormat(vatNumber, opts...)
+	if err != nil {
+		return err
 	}
+	return c.ValidateExistsContext(ctx, vatNumber, opts...)
+}
 
+// ValidateExists validates that the given VAT number exists in the external lookup service.
+func (c *Client) ValidateExists(vatNumber string, optsSlice ...ValidatorOpts) error {
+	vatNumber = normalizeVATNumber(vatNumber)
 	if len(vatNumber) < 3 {
 		return ErrInvalidVATNumberFormat
 	}
 
-	vatNumber = strings.ToUpper(vatNumber)
+	lookupService, err := c.lookupServiceFor(vatNumber, firstOpts(optsSlice))
+	if err != nil {
+		return err
+	}
+	return lookupService.Validate(vatNumber, firstOpts(optsSlice))
+}
 
-	pattern, ok := patterns[vatNumber[0:2]]
-	if !ok {
-		return ErrInvalidCountryCode
+// ValidateExistsContext is like ValidateExists, but the context is used for the call to the external lookup service.
+func (c *Client) ValidateExistsContext(ctx context.Context, vatNumber string, optsSlice ...ValidatorOpts) error {
+	vatNumber = normalizeVATNumber(vatNumber)
+	if len(vatNumber) < 3 {
+		return ErrInvalidVATNumberFormat
 	}
 
-	matched, err := regexp.MatchString(fmt.Sprintf("^%s$", pattern), vatNumber[2:])
+	lookupService, err := c.lookupServiceFor(vatNumber, firstOpts(optsSlice))
 	if err != nil {
 		return err
 	}
-	if !matched {
-		return ErrInvalidVATNumberFormat
+	return lookupService.ValidateContext(ctx, vatNumber, firstOpts(optsSlice))
+}
+
+// Lookup validates a VAT number by its format and returns the details the external lookup service holds for it.
+func (c *Client) Lookup(vatNumber string, opts ...ValidatorOpts) (*ValidationResult, error) {
+	err := ValidateFormat(vatNumber, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return c.LookupExists(vatNumber, opts...)
 }
 
-// ValidateExists validates that the given VAT number exists in the external lookup service.
-func ValidateExists(vatNumber string, optsSlice ...ValidatorOpts) error {
+// LookupContext is like Lookup, but the context is used for the call to the external lookup service.
+func (c *Client) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ...ValidatorOpts,
+) (*ValidationResult, error) {
+	err := ValidateFormat(vatNumber, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.LookupExistsContext(ctx, vatNumber, opts...)
+}
+
+// LookupExists returns the details the external lookup service holds for the given VAT number,
+// without validating its format first.
+func (c *Client) LookupExists(vatNumber string, optsSlice ...ValidatorOpts) (*ValidationResult, error) {
+	vatNumber = normalizeVATNumber(vatNumber)
 	if len(vatNumber) < 3 {
-		return ErrInvalidVATNumberFormat
+		return nil, ErrInvalidVATNumberFormat
 	}
 
-	vatNumber = strings.ToUpper(vatNumber)
+	lookupService, err := c.lookupServiceFor(vatNumber, firstOpts(optsSlice))
+	if err != nil {
+		return nil, err
+	}
+	return lookupService.Lookup(vatNumber, firstOpts(optsSlice))
+}
 
-	lookupService := ViesLookupService
-	if strings.HasPrefix(vatNumber, "GB") {
-		lookupService = UKVATLookupService
+// LookupExistsContext is like LookupExists, but the context is used for the call to the external lookup service.
+func (c *Client) LookupExistsContext(
+	ctx context.Context,
+	vatNumber string,
+	optsSlice ...ValidatorOpts,
+) (*ValidationResult, error) {
+	vatNumber = normalizeVATNumber(vatNumber)
+	if len(vatNumber) < 3 {
+		return nil, ErrInvalidVATNumberFormat
 	}
 
-	opts := ValidatorOpts{}
-	if len(optsSlice) > 0 {
-		opts = optsSlice[0]
+	lookupService, err := c.lookupServiceFor(vatNumber, firstOpts(optsSlice))
+	if err != nil {
+		return nil, err
 	}
+	return lookupService.LookupContext(ctx, vatNumber, firstOpts(optsSlice))
+}
 
-	return lookupService.Validate(vatNumber, opts)
+// lookupServiceFor returns the external lookup service that handles the given normalized VAT number.
+// An error is returned if the service is known to be unable to handle it right now.
+func (c *Client) lookupServiceFor(vatNumber string, opts ValidatorOpts) (LookupServiceInterface, error) {
+	switch service := lookupServiceName(vatNumber, opts); service {
+	case ServiceHMRC:
+		if strings.HasPrefix(vatNumber, "XI") {
+			return &xiService{uk: c.getUKLookupService()}, nil
+		}
+		return c.getUKLookupService(), nil
+	case ServiceUID:
+		return c.getCHLookupService(), nil
+	case ServiceBRREG:
+		return c.getNOLookupService(), nil
+	case ServiceVIES:
+		if p := c.viesStatusPoller.Load(); p != nil {
+			if err := p.checkMemberState(vatNumber[0:2]); err != nil {
+				return nil, err
+			}
+		}
+		if strings.HasPrefix(vatNumber, "XI") && opts.XIRouting == XIRoutingBoth {
+			return &xiService{vies: c.getViesLookupService(), uk: c.getUKLookupService()}, nil
+		}
+		return c.getViesLookupService(), nil
+	default:
+		rule, _ := countryRuleFor(vatNumber[0:2])
+		return c.wrapLookupService(rule.LookupService, service, strings.ToLower(service)+":"), nil
+	}
+}
+
+// lookupServiceName returns the name of the lookup service the VAT number is looked up with: one of the Service
+// constants, or the country code of a CountryRule with its own lookup service.
+func lookupServiceName(vatNumber string, opts ValidatorOpts) string {
+	if rule, ok := countryRuleFor(vatNumber[0:2]); ok && rule.LookupService != nil {
+		return rule.CountryCode
+	}
+	switch {
+	case strings.HasPrefix(vatNumber, "GB"), strings.HasPrefix(vatNumber, "XI") && opts.XIRouting == XIRoutingHMRC:
+		return ServiceHMRC
+	// Switzerland and Norway aren't part of the EU, so VIES doesn't know about their numbers
+	case strings.HasPrefix(vatNumber, "CH"):
+		return ServiceUID
+	case strings.HasPrefix(vatNumber, "NO"):
+		return ServiceBRREG
+	}
+	return ServiceVIES
+}
+
+// firstOpts returns the first of the optional ValidatorOpts, or the zero value if none were passed in.
+func firstOpts(optsSlice []ValidatorOpts) ValidatorOpts {
+	if len(optsSlice) > 0 {
+		return optsSlice[0]
+	}
+	return ValidatorOpts{}
 }
 
 // ValidatorOpts are options for the VAT number validator.
@@ -104,4 +241,19 @@ type ValidatorOpts struct {
 	UKClientSecret string
 	UKAccessToken  *UKAccessToken
 	IsUKTest       bool
+
+	// UKTokenSource provides access tokens for the UK VAT API when UKAccessToken isn't set or has expired.
+	// It takes precedence over the token source the client was created with, and must have been created with the
+	// same IsUKTest.
+	UKTokenSource *UKTokenSource
+
+	// UKRequesterVRN is your own GB VAT registration number. If it is set, lookups with the UK VAT API return a
+	// consultation number in ValidationResult.ConsultationNumber, which serves as proof that the check was made.
+	UKRequesterVRN string
+
+	// SkipChecksum skips validating the check digits of VAT numbers, only their format is validated
+	SkipChecksum bool
+
+	// XIRouting sets which service Northern Ireland numbers starting with XI are checked with. Defaults to VIES.
+	XIRouting XIRouting
 }
@@ -1,9 +1,11 @@
 package vat
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 )
@@ -56,7 +58,7 @@ var tests = []struct {
 	{"GB999999973", nil},
 	{"GB156730098481", nil},
 	{"GBGD549", ErrInvalidVATNumberFormat},
-	{"GBHA549", ErrInvalidVATNumberFormat},
+	{"GBHA549", nil},
 	{"GB99999997", ErrInvalidVATNumberFormat},
 	{"HU12345678", nil},
 	{"HU1234567", ErrInvalidVATNumberFormat},
@@ -78,6 +80,9 @@ var tests = []struct {
 	{"NL123456789B01", nil},
 	{"NL123456789B12", nil},
 	{"NL12345678B12", ErrInvalidVATNumberFormat},
+	{"NO123456789", nil},
+	{"NO123456789MVA", nil},
+	{"NO12345678", ErrInvalidVATNumberFormat},
 	{"PL1234567890", nil},
 	{"PL123456789", ErrInvalidVATNumberFormat},
 	{"PT123456789", nil},
@@ -131,7 +136,9 @@ var tests = []struct {
 	{"GB999979973", nil},
 	{"GB156730984812", nil},
 	{"GBGD529", ErrInvalidVATNumberFormat},
-	{"GBHA519", ErrInvalidVATNumberFormat},
+	{"GBHA519", nil},
+	{"GBHA499", ErrInvalidVATNumberFormat},
+	{"GBGD499", nil},
 	{"GB99997997", ErrInvalidVATNumberFormat},
 	{"GB15673098481", ErrInvalidVATNumberFormat},
 	{"HU12342678", nil},
@@ -164,12 +171,141 @@ func BenchmarkValidateFormat(b *testing.B) {
 	}
 }
 
+// TestValidateFormat tests the patterns only, most of the numbers above don't have valid check digits.
 func TestValidateFormat(t *testing.T) {
 	for _, test := range tests {
+		err := ValidateFormat(test.number, ValidatorOpts{SkipChecksum: true})
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("Expected <%v> for %v, got <%v>", test.expectedError, test.number, err)
+		}
+	}
+}
+
+var checksumTests = []struct {
+	number        string
+	expectedError error
+}{
+	{"ATU13585627", nil},
+	{"ATU13585626", ErrInvalidVATNumberChecksum},
+	{"ATU1358562A", ErrInvalidVATNumberChecksum},
+	{"BE0403019261", nil},
+	{"BE0472429986", nil},
+	{"BE0403019262", ErrInvalidVATNumberChecksum},
+	{"BG175074752", nil},
+	{"BG7523169263", nil}, // personal number
+	{"BG8032056031", nil}, // foreigner number
+	{"BG175074753", ErrInvalidVATNumberChecksum},
+	{"BG7523169264", ErrInvalidVATNumberChecksum},
+	{"CHE100155212", nil},
+	{"CHE-113.690.319 MWST", nil},
+	{"CHE-113.690.319 TVA", nil},
+	{"CHE100155213", ErrInvalidVATNumberChecksum},
+	{"CY10259033P", nil},
+	{"CY10259033Q", ErrInvalidVATNumberChecksum},
+	{"CY12000000C", ErrInvalidVATNumberChecksum},
+	{"CZ25123891", nil},
+	{"CZ640903926", nil},  // special case
+	{"CZ395601439", nil},  // birth number before 1954
+	{"CZ7103192745", nil}, // birth number
+	{"CZ25123892", ErrInvalidVATNumberChecksum},
+	{"CZ7103192746", ErrInvalidVATNumberChecksum},
+	{"CZ7113192745", ErrInvalidVATNumberChecksum},
+	{"DE136695976", nil},
+	{"DE123456789", ErrInvalidVATNumberChecksum},
+	{"DK13585628", nil},
+	{"DK13585627", ErrInvalidVATNumberChecksum},
+	{"EE100931558", nil},
+	{"EE100594102", nil},
+	{"EE100931559", ErrInvalidVATNumberChecksum},
+	{"EL094259216", nil},
+	{"EL094259217", ErrInvalidVATNumberChecksum},
+	{"ESB58378431", nil},
+	{"ESQ2876031B", nil},
+	{"ESX5253868R", nil},
+	{"ES54362315K", nil},
+	{"ESB58378432", ErrInvalidVATNumberChecksum},
+	{"ESX5253868T", ErrInvalidVATNumberChecksum},
+	{"ES54362315Z", ErrInvalidVATNumberChecksum},
+	{"FI20774740", nil},
+	{"FI20774741", ErrInvalidVATNumberChecksum},
+	{"FR40303265045", nil},
+	{"FRK7399859412", nil},
+	{"FR41303265045", ErrInvalidVATNumberChecksum},
+	{"FRK7399859413", ErrInvalidVATNumberChecksum},
+	{"FR0J303265046", ErrInvalidVATNumberChecksum}, // valid key, but the SIREN fails the Luhn check
+	{"GB999999973", nil},
+	{"GB333289454", nil},
+	{"GB434031439", nil}, // mod 9755
+	{"GB999999973001", nil},
+	{"GBGD001", nil},
+	{"GB999999974", ErrInvalidVATNumberChecksum},
+	{"GB999999974001", ErrInvalidVATNumberChecksum},
+	{"HR33392005961", nil},
+	{"HR33392005962", ErrInvalidVATNumberChecksum},
+	{"HU12892312", nil},
+	{"HU12892313", ErrInvalidVATNumberChecksum},
+	{"IE6433435F", nil},
+	{"IE6433435OA", nil},
+	{"IE8D79739I", nil},
+	{"IE6433435E", ErrInvalidVATNumberChecksum},
+	{"IE8D79739J", ErrInvalidVATNumberChecksum},
+	{"IT00743110157", nil},
+	{"IT00743110158", ErrInvalidVATNumberChecksum},
+	{"IT00000000000", ErrInvalidVATNumberChecksum},
+	{"IT12345672001", ErrInvalidVATNumberChecksum}, // office code out of range
+	{"LT119511515", nil},
+	{"LT100001919017", nil},
+	{"LT100004801610", nil},
+	{"LT119511516", ErrInvalidVATNumberChecksum},
+	{"LT100001919018", ErrInvalidVATNumberChecksum},
+	{"LU15027442", nil},
+	{"LU15027443", ErrInvalidVATNumberChecksum},
+	{"LV40003521600", nil},
+	{"LV16117519997", nil}, // personal code
+	{"LV32579461005", nil}, // personal code issued since 2017, without a check digit
+	{"LV40003521601", ErrInvalidVATNumberChecksum},
+	{"LV16117519998", ErrInvalidVATNumberChecksum},
+	{"LV16137519997", ErrInvalidVATNumberChecksum}, // invalid date of birth
+	{"MT11679112", nil},
+	{"MT11679113", ErrInvalidVATNumberChecksum},
+	{"NL004495445B01", nil},
+	{"NL123456789B13", nil}, // sole trader number validated with mod 97
+	{"NL004495446B01", ErrInvalidVATNumberChecksum},
+	{"NL000000000B00", ErrInvalidVATNumberChecksum},
+	{"NO995525828", nil},
+	{"NO 988 077 917 MVA", nil},
+	{"NO995525829", ErrInvalidVATNumberChecksum},
+	{"NO100000130", ErrInvalidVATNumberChecksum}, // check digit would be 10
+	{"PL8567346215", nil},
+	{"PL8567346216", ErrInvalidVATNumberChecksum},
+	{"PT501964843", nil},
+	{"PT501964842", ErrInvalidVATNumberChecksum},
+	{"RO18547290", nil},
+	{"RO24736200", nil},
+	{"RO18547291", ErrInvalidVATNumberChecksum},
+	{"SE123456789701", nil},
+	{"SE123456789801", ErrInvalidVATNumberChecksum},
+	{"SE123456789702", ErrInvalidVATNumberChecksum},
+	{"SI50223054", nil},
+	{"SI50223055", ErrInvalidVATNumberChecksum},
+	{"SK2022749619", nil},
+	{"SK2022749618", ErrInvalidVATNumberChecksum},
+	{"XI999999973", nil},
+	{"XI999999974", ErrInvalidVATNumberChecksum},
+}
+
+func TestValidateChecksum(t *testing.T) {
+	for _, test := range checksumTests {
 		err := ValidateFormat(test.number)
 		if !errors.Is(err, test.expectedError) {
 			t.Errorf("Expected <%v> for %v, got <%v>", test.expectedError, test.number, err)
 		}
+		if err != nil && !errors.Is(err, ErrInvalidVATNumberFormat) {
+			t.Errorf("Expected <%v> to match <%v>", err, ErrInvalidVATNumberFormat)
+		}
+		if err = ValidateFormat(test.number, ValidatorOpts{SkipChecksum: true}); err != nil {
+			t.Errorf("Expected <nil> for %v without checksum, got <%v>", test.number, err)
+		}
 	}
 }
 
@@ -197,11 +333,13 @@ func TestValidateExists(t *testing.T) {
 		{"GB333289454", mockUKVATService, nil},
 		// XI is Northern Ireland, which while part of the UK is actually still validated by VIES
 		{"XI0472429986", mockViesService, nil},
+		{"be 0472.429.986", mockViesService, nil},
 	}
 
 	for _, test := range lookupTests {
-		if len(test.vatNumber) >= 3 {
-			test.service.EXPECT().Validate(test.vatNumber, ValidatorOpts{}).Return(test.expectedError)
+		// the lookup service is passed the normalized number
+		if compact, _ := Normalize(test.vatNumber); len(compact) >= 3 {
+			test.service.EXPECT().Validate(compact, ValidatorOpts{}).Return(test.expectedError)
 		}
 
 		err := ValidateExists(test.vatNumber)
@@ -215,3 +353,109 @@ func restoreLookupServices() {
 	ViesLookupService = &viesService{}
 	UKVATLookupService = &ukVATService{}
 }
+
+func TestLookupExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockViesService := NewMockLookupServiceInterface(ctrl)
+	mockUKVATService := NewMockLookupServiceInterface(ctrl)
+	ViesLookupService = mockViesService
+	UKVATLookupService = mockUKVATService
+
+	defer restoreLookupServices()
+
+	viesResult := &ValidationResult{CountryCode: "NL", VATNumber: "123456789B01", Valid: true, Service: ServiceVIES}
+	ukResult := &ValidationResult{CountryCode: "GB", VATNumber: "333289454", Service: ServiceHMRC}
+
+	mockViesService.EXPECT().Lookup("NL123456789B01", ValidatorOpts{}).Return(viesResult, nil)
+	mockUKVATService.EXPECT().Lookup("GB333289454", ValidatorOpts{}).Return(ukResult, nil)
+
+	r, err := LookupExists("nl123456789B01")
+	if err != nil || r != viesResult {
+		t.Errorf("Expected VIES result for NL123456789B01, got <%v> <%v>", r, err)
+	}
+	r, err = LookupExists("GB333289454")
+	if err != nil || r != ukResult {
+		t.Errorf("Expected UK result for GB333289454, got <%v> <%v>", r, err)
+	}
+	if _, err = LookupExists("Hi"); !errors.Is(err, ErrInvalidVATNumberFormat) {
+		t.Errorf("Expected <%v> for Hi, got <%v>", ErrInvalidVATNumberFormat, err)
+	}
+}
+
+func TestParseViesDate(t *testing.T) {
+	d := parseViesDate("2015-03-06+01:00")
+	if d.Year() != 2015 || d.Month() != 3 || d.Day() != 6 {
+		t.Errorf("Expected 2015-03-06, got %v", d)
+	}
+	if d := parseViesDate("not a date"); !d.IsZero() {
+		t.Errorf("Expected zero time, got %v", d)
+	}
+}
+
+func TestValidateExistsContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockViesService := NewMockLookupServiceInterface(ctrl)
+	ViesLookupService = mockViesService
+
+	defer restoreLookupServices()
+
+	ctx := context.Background()
+	mockViesService.EXPECT().ValidateContext(ctx, "NL123456789B01", ValidatorOpts{}).Return(nil)
+
+	if err := ValidateExistsContext(ctx, "NL123456789B01"); err != nil {
+		t.Errorf("Expected <nil> for NL123456789B01, got <%v>", err)
+	}
+}
+
+func TestValidateExistsContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, vatNumber := range []string{"NL123456789B01", "GB333289454"} {
+		err := ValidateExistsContext(ctx, vatNumber, ValidatorOpts{UKAccessToken: &UKAccessToken{
+			Token:     "token",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected <%v> for %v, got <%v>", context.Canceled, vatNumber, err)
+		}
+	}
+}
+
+func TestLookupServiceName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	if err := RegisterCountry(CountryRule{
+		CountryCode:   "ZZ",
+		Pattern:       `[0-9]{6}`,
+		LookupService: NewMockLookupServiceInterface(ctrl),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterCountry("ZZ")
+
+	tests := []struct {
+		vatNumber string
+		opts      ValidatorOpts
+		service   string
+	}{
+		{"BE0472429986", ValidatorOpts{}, ServiceVIES},
+		{"GB999999973", ValidatorOpts{}, ServiceHMRC},
+		{"XI999999973", ValidatorOpts{}, ServiceVIES},
+		{"XI999999973", ValidatorOpts{XIRouting: XIRoutingHMRC}, ServiceHMRC},
+		{"XI999999973", ValidatorOpts{XIRouting: XIRoutingBoth}, ServiceVIES},
+		{"CHE113690319", ValidatorOpts{}, ServiceUID},
+		{"NO995525828", ValidatorOpts{}, ServiceBRREG},
+		{"ZZ123450", ValidatorOpts{}, "ZZ"},
+	}
+	for _, test := range tests {
+		if s := lookupServiceName(test.vatNumber, test.opts); s != test.service {
+			t.Errorf("Expected %s for %s %+v, got %s", test.service, test.vatNumber, test.opts, s)
+		}
+	}
+}
@@ -6,7 +6,7 @@ It also offers UK VAT number validation using a UK VAT API.
 
 Validate a VAT number
 
-	err := vat.Validate("NL123456789B01")
+	err := vat.Validate("NL004495445B01")
 
 Get VAT rate that is currently in effect for a given country
 
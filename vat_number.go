@@ -0,0 +1,134 @@
+package vat
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// VATNumber is a normalized VAT number, split into its country code and the rest. Create one with Parse, which
+// validates its format and check digits. Numbers read with UnmarshalJSON have a supported country code and a valid
+// format, but their check digits aren't validated again. Numbers read with Scan are only normalized, so that numbers
+// stored earlier can always be read back.
+// The zero value represents no VAT number, which is stored as NULL in JSON and SQL.
+type VATNumber struct {
+	countryCode string
+	number      string
+}
+
+// Parse normalizes a VAT number and validates its format and check digits, see Normalize and ValidateFormat.
+// It does not check whether the number exists.
+func Parse(s string) (VATNumber, error) {
+	compact := normalizeVATNumber(s)
+	if err := ValidateFormat(compact); err != nil {
+		return VATNumber{}, err
+	}
+	return VATNumber{countryCode: compact[0:2], number: compact[2:]}, nil
+}
+
+// CountryCode returns the country code the VAT number starts with, e.g. "EL" for Greece.
+func (n VATNumber) CountryCode() string {
+	return n.countryCode
+}
+
+// Country returns the ISO 3166 code of the country that issued the VAT number. This differs from the country code
+// for Greece, which is "GR", and Northern Ireland, which is "GB-NIR".
+func (n VATNumber) Country() string {
+	switch n.countryCode {
+	case "EL":
+		return "GR"
+	case "XI":
+		return "GB-NIR"
+	default:
+		return n.countryCode
+	}
+}
+
+// Number returns the VAT number without its country code.
+func (n VATNumber) Number() string {
+	return n.number
+}
+
+// String returns the VAT number in its compact form, e.g. "BE0403019261".
+func (n VATNumber) String() string {
+	return n.countryCode + n.number
+}
+
+// Format returns the VAT number in the form the country commonly displays it in, e.g. "BE 0403.019.261".
+func (n VATNumber) Format() string {
+	return formatDisplay(n.String())
+}
+
+// IsZero returns whether n is the zero value.
+func (n VATNumber) IsZero() bool {
+	return n.countryCode == ""
+}
+
+// MarshalJSON encodes the VAT number as a string in its compact form, or null for the zero value.
+func (n VATNumber) MarshalJSON() ([]byte, error) {
+	if n.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON reads a VAT number from a JSON string. Null and the empty string result in the zero value.
+// The number is normalized and its format is validated, but its check digits aren't, so that numbers that were
+// validated with SkipChecksum, like HMRC sandbox numbers, can be read back.
+func (n *VATNumber) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var v VATNumber
+	if err := v.parse(s); err != nil {
+		return err
+	}
+	if !v.IsZero() {
+		if err := ValidateFormat(v.String(), ValidatorOpts{SkipChecksum: true}); err != nil {
+			return err
+		}
+	}
+	*n = v
+	return nil
+}
+
+// Scan implements sql.Scanner, so that VAT numbers can be read from a text column. The number is normalized but not
+// validated again, so that numbers that were stored earlier can be read back even if they wouldn't pass
+// ValidateFormat today, e.g. because the rules for their country changed. Use Parse to validate them.
+func (n *VATNumber) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		return n.parse(nil)
+	case string:
+		return n.parse(&src)
+	case []byte:
+		s := string(src)
+		return n.parse(&s)
+	default:
+		return fmt.Errorf("vat: cannot scan %T into VATNumber", src)
+	}
+}
+
+// Value implements driver.Valuer, the VAT number is stored in its compact form or as NULL for the zero value.
+func (n VATNumber) Value() (driver.Value, error) {
+	if n.IsZero() {
+		return nil, nil
+	}
+	return n.String(), nil
+}
+
+// parse sets n to the stored VAT number, or the zero value if s is nil or empty. The number is only normalized and
+// split into its country code and the rest; it is not validated.
+func (n *VATNumber) parse(s *string) error {
+	if s == nil || *s == "" {
+		*n = VATNumber{}
+		return nil
+	}
+	compact := normalizeVATNumber(*s)
+	if len(compact) < 3 || !startsWithLetter(compact) || !startsWithLetter(compact[1:]) {
+		return ErrInvalidVATNumberFormat
+	}
+	*n = VATNumber{countryCode: compact[0:2], number: compact[2:]}
+	return nil
+}
@@ -0,0 +1,137 @@
+package vat
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input         string
+		countryCode   string
+		country       string
+		number        string
+		formatted     string
+		expectedError error
+	}{
+		{"BE0403019261", "BE", "BE", "0403019261", "BE 0403.019.261", nil},
+		{"be 0403.019.261", "BE", "BE", "0403019261", "BE 0403.019.261", nil},
+		{"GR094259216", "EL", "GR", "094259216", "EL094259216", nil},
+		{"XI999999973", "XI", "GB-NIR", "999999973", "XI 999 9999 73", nil},
+		{"GB999999973", "GB", "GB", "999999973", "GB 999 9999 73", nil},
+		{"BE0403019262", "", "", "", "", ErrInvalidVATNumberChecksum},
+		{"XX123456789", "", "", "", "", ErrInvalidCountryCode},
+		{"", "", "", "", "", ErrInvalidVATNumberFormat},
+	}
+
+	for _, test := range tests {
+		n, err := Parse(test.input)
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("Expected <%v> for %q, got <%v>", test.expectedError, test.input, err)
+			continue
+		}
+		if err != nil {
+			if !n.IsZero() {
+				t.Errorf("Expected the zero value for %q, got %v", test.input, n)
+			}
+			continue
+		}
+		if n.CountryCode() != test.countryCode || n.Country() != test.country || n.Number() != test.number {
+			t.Errorf("Unexpected parts for %q: %q %q %q", test.input, n.CountryCode(), n.Country(), n.Number())
+		}
+		if n.String() != test.countryCode+test.number {
+			t.Errorf("Expected %q for %q, got %q", test.countryCode+test.number, test.input, n.String())
+		}
+		if n.Format() != test.formatted {
+			t.Errorf("Expected %q for %q, got %q", test.formatted, test.input, n.Format())
+		}
+	}
+}
+
+func TestVATNumberJSON(t *testing.T) {
+	type payload struct {
+		VATNumber VATNumber `json:"vat_number"`
+	}
+
+	n, err := Parse("nl 0044.954.45.B01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(payload{VATNumber: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"vat_number":"NL004495445B01"}` {
+		t.Errorf("Unexpected JSON: %s", b)
+	}
+
+	var p payload
+	if err = json.Unmarshal(b, &p); err != nil || p.VATNumber != n {
+		t.Errorf("Expected %v, got %v <%v>", n, p.VATNumber, err)
+	}
+
+	b, _ = json.Marshal(payload{})
+	if string(b) != `{"vat_number":null}` {
+		t.Errorf("Unexpected JSON for the zero value: %s", b)
+	}
+	for _, data := range []string{`{"vat_number":null}`, `{"vat_number":""}`} {
+		p = payload{VATNumber: n}
+		if err = json.Unmarshal([]byte(data), &p); err != nil || !p.VATNumber.IsZero() {
+			t.Errorf("Expected the zero value for %s, got %v <%v>", data, p.VATNumber, err)
+		}
+	}
+
+	// stored numbers aren't validated again
+	err = json.Unmarshal([]byte(`{"vat_number":"NL004495446B01"}`), &p)
+	if err != nil || p.VATNumber.String() != "NL004495446B01" {
+		t.Errorf("Expected NL004495446B01, got %v <%v>", p.VATNumber, err)
+	}
+	// but their format is
+	for data, expected := range map[string]error{
+		`{"vat_number":"12"}`:            ErrInvalidVATNumberFormat,
+		`{"vat_number":"ZZ!!<script>"}`:  ErrInvalidCountryCode,
+		`{"vat_number":"NL0044954B01"}`:  ErrInvalidVATNumberFormat,
+		`{"vat_number":"BE04030192610"}`: ErrInvalidVATNumberFormat,
+	} {
+		p = payload{VATNumber: n}
+		if err = json.Unmarshal([]byte(data), &p); !errors.Is(err, expected) || p.VATNumber != n {
+			t.Errorf("Expected <%v> for %s, got %v <%v>", expected, data, p.VATNumber, err)
+		}
+	}
+}
+
+func TestVATNumberSQL(t *testing.T) {
+	n, err := Parse("DE136695976")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := n.Value()
+	if err != nil || v != driver.Value("DE136695976") {
+		t.Errorf("Expected DE136695976, got %v <%v>", v, err)
+	}
+	if v, err = (VATNumber{}).Value(); err != nil || v != nil {
+		t.Errorf("Expected nil for the zero value, got %v <%v>", v, err)
+	}
+
+	var scanned VATNumber
+	for _, src := range []interface{}{"DE136695976", []byte("DE136695976")} {
+		if err = scanned.Scan(src); err != nil || scanned != n {
+			t.Errorf("Expected %v for %v, got %v <%v>", n, src, scanned, err)
+		}
+	}
+	if err = scanned.Scan(nil); err != nil || !scanned.IsZero() {
+		t.Errorf("Expected the zero value for NULL, got %v <%v>", scanned, err)
+	}
+	if err = scanned.Scan(42); err == nil {
+		t.Error("Expected an error when scanning an int")
+	}
+	// numbers that don't pass ValidateFormat, like HMRC sandbox numbers, can be read back
+	if err = scanned.Scan("GB553557881"); err != nil || scanned.CountryCode() != "GB" || scanned.Number() != "553557881" {
+		t.Errorf("Expected GB553557881, got %v <%v>", scanned, err)
+	}
+	if err = scanned.Scan("123456789"); !errors.Is(err, ErrInvalidVATNumberFormat) {
+		t.Errorf("Expected <%v>, got <%v>", ErrInvalidVATNumberFormat, err)
+	}
+}
@@ -0,0 +1,232 @@
+package vat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ViesRESTLookupService validates EU VAT numbers with the VIES REST API instead of the SOAP service.
+// Set ViesLookupService to it, or create a Client with WithViesBackend(ViesBackendREST), to switch over.
+var ViesRESTLookupService LookupServiceInterface = &viesRESTService{}
+
+// viesRESTService validates EU VAT numbers with the VIES REST API
+type viesRESTService struct {
+	client *Client
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *viesRESTService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
+
+// ValidateContext is like Validate, but the context is used for the call to VIES.
+func (s *viesRESTService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details VIES holds for the given VAT number.
+// A number that VIES does not know about is reported through ValidationResult.Valid rather than an error.
+func (s *viesRESTService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
+
+// LookupContext is like Lookup, but the context is used for the call to VIES.
+func (s *viesRESTService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	_ ValidatorOpts,
+) (*ValidationResult, error) {
+	r, err := s.LookupApproxContext(ctx, ApproxRequest{VATNumber: vatNumber})
+	if err != nil {
+		return nil, err
+	}
+	return &r.ValidationResult, nil
+}
+
+// LookupApproxContext checks the VAT number with VIES and compares the trader details against the ones on record.
+func (s *viesRESTService) LookupApproxContext(ctx context.Context, req ApproxRequest) (*ApproxResult, error) {
+	if len(req.VATNumber) < 3 {
+		return nil, ErrInvalidVATNumberFormat
+	}
+
+	n := strings.ToUpper(req.VATNumber)
+	body := viesRESTRequest{
+		CountryCode:       n[0:2],
+		VATNumber:         n[2:],
+		TraderName:        req.TraderName,
+		TraderCompanyType: req.TraderCompanyType,
+		TraderStreet:      req.TraderStreet,
+		TraderPostalCode:  req.TraderPostcode,
+		TraderCity:        req.TraderCity,
+	}
+	if r := strings.ToUpper(req.RequesterVATNumber); len(r) > 2 {
+		body.RequesterMemberStateCode = r[0:2]
+		body.RequesterNumber = r[2:]
+	}
+
+	var rd viesRESTResponse
+	if err := s.call(ctx, "POST", "/check-vat-number", body, &rd); err != nil {
+		return nil, err
+	}
+
+	return &ApproxResult{
+		ValidationResult: ValidationResult{
+			CountryCode:        rd.CountryCode,
+			VATNumber:          rd.VATNumber,
+			Valid:              rd.Valid,
+			Name:               cleanViesField(rd.Name),
+			Address:            cleanViesField(rd.Address),
+			RequestDate:        parseViesRESTDate(rd.RequestDate),
+			Service:            ServiceVIES,
+			Attempts:           1,
+			ConsultationNumber: rd.RequestIdentifier,
+		},
+		TraderCompanyType: cleanViesField(rd.TraderCompanyType),
+		TraderStreet:      cleanViesField(rd.TraderStreet),
+		TraderPostcode:    cleanViesField(rd.TraderPostalCode),
+		TraderCity:        cleanViesField(rd.TraderCity),
+		NameMatch:         parseMatchResult(rd.TraderNameMatch),
+		CompanyTypeMatch:  parseMatchResult(rd.TraderCompanyTypeMatch),
+		StreetMatch:       parseMatchResult(rd.TraderStreetMatch),
+		PostcodeMatch:     parseMatchResult(rd.TraderPostalCodeMatch),
+		CityMatch:         parseMatchResult(rd.TraderCityMatch),
+	}, nil
+}
+
+// call sends a request to the VIES REST API and decodes the response into v,
+// or returns an error if VIES reported one
+func (s *viesRESTService) call(ctx context.Context, method, path string, body, v interface{}) error {
+	c := s.getClient()
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.getViesRESTURL()+path, reqBody)
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	jsonRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+
+	var errRes struct {
+		ActionSucceed *bool `json:"actionSucceed"`
+		ErrorWrappers []struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		} `json:"errorWrappers"`
+		UserError string `json:"userError"`
+	}
+	if err = json.Unmarshal(jsonRes, &errRes); err != nil {
+		if res.StatusCode != http.StatusOK {
+			return ErrServiceUnavailable{Err: fmt.Errorf("unexpected status code from VIES: %d", res.StatusCode)}
+		}
+		return ErrServiceUnavailable{Err: err}
+	}
+	if len(errRes.ErrorWrappers) > 0 {
+		e := errRes.ErrorWrappers[0]
+		return &ViesFaultError{Code: parseViesFaultCode(e.Error), Message: e.Message}
+	}
+	if u := errRes.UserError; u != "" && u != "VALID" && u != "INVALID" {
+		return &ViesFaultError{Code: parseViesFaultCode(u), Message: u}
+	}
+	if res.StatusCode != http.StatusOK || (errRes.ActionSucceed != nil && !*errRes.ActionSucceed) {
+		return ErrServiceUnavailable{Err: fmt.Errorf("unexpected status code from VIES: %d", res.StatusCode)}
+	}
+
+	if err = json.Unmarshal(jsonRes, v); err != nil {
+		return ErrServiceUnavailable{Err: err}
+	}
+	return nil
+}
+
+func (s *viesRESTService) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	return defaultClient
+}
+
+// viesRESTRequest is the request body of the check-vat-number endpoint
+type viesRESTRequest struct {
+	CountryCode              string `json:"countryCode"`
+	VATNumber                string `json:"vatNumber"`
+	RequesterMemberStateCode string `json:"requesterMemberStateCode,omitempty"`
+	RequesterNumber          string `json:"requesterNumber,omitempty"`
+	TraderName               string `json:"traderName,omitempty"`
+	TraderCompanyType        string `json:"traderCompanyType,omitempty"`
+	TraderStreet             string `json:"traderStreet,omitempty"`
+	TraderPostalCode         string `json:"traderPostalCode,omitempty"`
+	TraderCity               string `json:"traderCity,omitempty"`
+}
+
+// viesRESTResponse is the response body of the check-vat-number endpoint
+type viesRESTResponse struct {
+	CountryCode            string `json:"countryCode"`
+	VATNumber              string `json:"vatNumber"`
+	RequestDate            string `json:"requestDate"`
+	Valid                  bool   `json:"valid"`
+	RequestIdentifier      string `json:"requestIdentifier"`
+	Name                   string `json:"name"`
+	Address                string `json:"address"`
+	TraderName             string `json:"traderName"`
+	TraderStreet           string `json:"traderStreet"`
+	TraderPostalCode       string `json:"traderPostalCode"`
+	TraderCity             string `json:"traderCity"`
+	TraderCompanyType      string `json:"traderCompanyType"`
+	TraderNameMatch        string `json:"traderNameMatch"`
+	TraderStreetMatch      string `json:"traderStreetMatch"`
+	TraderPostalCodeMatch  string `json:"traderPostalCodeMatch"`
+	TraderCityMatch        string `json:"traderCityMatch"`
+	TraderCompanyTypeMatch string `json:"traderCompanyTypeMatch"`
+}
+
+// parseViesRESTDate parses the timestamps used by the VIES REST API, e.g. "2024-03-06T10:11:12.123Z"
+func parseViesRESTDate(d string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, d); err == nil {
+		return t
+	}
+	return parseViesDate(d)
+}
+
+// parseMatchResult maps a match result returned by the VIES REST API to a MatchResult
+func parseMatchResult(m string) MatchResult {
+	switch m {
+	case "VALID":
+		return MatchValid
+	case "INVALID":
+		return MatchInvalid
+	case "NOT_PROCESSED":
+		return MatchNotProcessed
+	default:
+		return MatchUnknown
+	}
+}
+
+const viesRESTServiceURL = "https://ec.europa.eu/taxation_customs/vies/rest-api"
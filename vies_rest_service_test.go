@@ -0,0 +1,116 @@
+package vat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newViesRESTTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/check-vat-number" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var req viesRESTRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Unexpected request body: %v", err)
+		}
+
+		switch req.VATNumber {
+		case "0472429986":
+			_, _ = io.WriteString(w, `{
+				"countryCode": "BE",
+				"vatNumber": "0472429986",
+				"requestDate": "2024-03-06T10:11:12.123Z",
+				"valid": true,
+				"requestIdentifier": "`+req.RequesterNumber+`",
+				"name": "NV ACME",
+				"address": "Main Street 1\n1000 Brussel",
+				"traderNameMatch": "VALID",
+				"traderCityMatch": "NOT_PROCESSED"
+			}`)
+		case "004495445B01":
+			_, _ = io.WriteString(w, `{"countryCode": "NL", "vatNumber": "004495445B01", "valid": false, "name": "---"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{
+				"actionSucceed": false,
+				"errorWrappers": [{"error": "MS_UNAVAILABLE", "message": "member state unavailable"}]
+			}`)
+		}
+	}))
+}
+
+func TestViesRESTService(t *testing.T) {
+	server := newViesRESTTestServer(t)
+	defer server.Close()
+
+	c := NewClient(WithViesBackend(ViesBackendREST), WithViesRESTURL(server.URL))
+
+	r, err := c.Lookup("BE0472429986")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Valid || r.Name != "NV ACME" || r.Address != "Main Street 1\n1000 Brussel" || r.Service != ServiceVIES {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+	if r.RequestDate.Format("2006-01-02") != "2024-03-06" {
+		t.Errorf("Expected request date 2024-03-06, got %v", r.RequestDate)
+	}
+
+	if err = c.Validate("NL004495445B01"); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v> for NL004495445B01, got <%v>", ErrVATNumberNotFound, err)
+	}
+
+	err = c.Validate("DE136695976")
+	var fault *ViesFaultError
+	if !errors.As(err, &fault) || fault.Code != ViesMSUnavailable || !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected MS_UNAVAILABLE fault for DE136695976, got <%v>", err)
+	}
+}
+
+func TestViesRESTServiceApprox(t *testing.T) {
+	server := newViesRESTTestServer(t)
+	defer server.Close()
+
+	c := NewClient(WithViesBackend(ViesBackendREST), WithViesRESTURL(server.URL))
+	r, err := c.CheckApprox(ApproxRequest{
+		VATNumber:          "BE0472429986",
+		RequesterVATNumber: "NL004495445B01",
+		TraderName:         "NV ACME",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ConsultationNumber != "004495445B01" || r.NameMatch != MatchValid || r.CityMatch != MatchNotProcessed {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
+
+func TestViesBackendsReturnSameResult(t *testing.T) {
+	restServer := newViesRESTTestServer(t)
+	defer restServer.Close()
+	soapServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	defer soapServer.Close()
+
+	soap, err := NewClient(WithViesURL(soapServer.URL)).Lookup("BE0472429986")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := NewClient(WithViesBackend(ViesBackendREST), WithViesRESTURL(restServer.URL)).Lookup("BE0472429986")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if soap.CountryCode != rest.CountryCode || soap.VATNumber != rest.VATNumber || soap.Valid != rest.Valid ||
+		soap.Name != rest.Name || soap.Address != rest.Address || soap.Service != rest.Service {
+		t.Errorf("Expected same results, got %+v and %+v", soap, rest)
+	}
+}
@@ -2,51 +2,54 @@ package vat
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // LookupServiceInterface is an interface for the service that calls external services to validate VATs.
 type LookupServiceInterface interface {
 	Validate(vatNumber string, opts ValidatorOpts) error
+	ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error
+	Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error)
+	LookupContext(ctx context.Context, vatNumber string, opts ValidatorOpts) (*ValidationResult, error)
 }
 
 // viesService validates EU VAT numbers with the VIES service
-type viesService struct{}
+type viesService struct {
+	client *Client
+}
 
 // Validate returns whether the given VAT number is valid or not
 // There currently are no VIES options, so the "opts" parameter is here for interface compliance but ignored
-func (s *viesService) Validate(vatNumber string, _ ValidatorOpts) error {
-	if len(vatNumber) < 3 {
-		return ErrInvalidVATNumberFormat
-	}
+func (s *viesService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return s.ValidateContext(context.Background(), vatNumber, opts)
+}
 
-	res, err := s.lookup(s.getEnvelope(vatNumber))
-	if err != nil {
-		return ErrServiceUnavailable{Err: err}
-	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
+// ValidateContext is like Validate, but the context is used for the call to VIES.
+func (s *viesService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
 
-	xmlRes, err := io.ReadAll(res.Body)
-	if err != nil {
-		return ErrServiceUnavailable{Err: err} // assume if we can't read the body then VIES gave us a bad response
-	}
+// Lookup returns the details VIES holds for the given VAT number.
+// A number that VIES does not know about is reported through ValidationResult.Valid rather than an error.
+func (s *viesService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.LookupContext(context.Background(), vatNumber, opts)
+}
 
-	// check if response contains "INVALID_INPUT" string
-	if bytes.Contains(xmlRes, []byte("INVALID_INPUT")) {
-		return ErrInvalidVATNumberFormat
+// LookupContext is like Lookup, but the context is used for the call to VIES.
+func (s *viesService) LookupContext(ctx context.Context, vatNumber string, _ ValidatorOpts) (*ValidationResult, error) {
+	if len(vatNumber) < 3 {
+		return nil, ErrInvalidVATNumberFormat
 	}
 
-	// check if response contains "MS_UNAVAILABLE" string
-	if bytes.Contains(xmlRes, []byte("MS_UNAVAILABLE")) {
-		return ErrServiceUnavailable{Err: errors.New("vies reports service is unavailable")}
-	} else if bytes.Contains(xmlRes, []byte("MS_MAX_CONCURRENT_REQ")) {
-		return ErrServiceUnavailable{Err: errors.New("max concurrent requests limit hit")}
+	xmlRes, err := s.call(ctx, s.getEnvelope(vatNumber))
+	if err != nil {
+		return nil, err
 	}
 
 	var rd struct {
@@ -65,22 +68,121 @@ func (s *viesService) Validate(vatNumber string, _ ValidatorOpts) error {
 		}
 	}
 	if err = xml.Unmarshal(xmlRes, &rd); err != nil {
-		return ErrServiceUnavailable{Err: err} // assume if response data doesn't match the struct, the service is down
+		return nil, ErrServiceUnavailable{Err: err} // assume if response data doesn't match the struct, the service is down
 	}
 
-	r := &viesResponse{
+	return &ValidationResult{
 		CountryCode: rd.Soap.Soap.CountryCode,
 		VATNumber:   rd.Soap.Soap.VATNumber,
-		RequestDate: rd.Soap.Soap.RequestDate,
 		Valid:       rd.Soap.Soap.Valid,
-		Name:        rd.Soap.Soap.Name,
-		Address:     rd.Soap.Soap.Address,
+		Name:        cleanViesField(rd.Soap.Soap.Name),
+		Address:     cleanViesField(rd.Soap.Soap.Address),
+		RequestDate: parseViesDate(rd.Soap.Soap.RequestDate),
+		Service:     ServiceVIES,
+		Attempts:    1,
+	}, nil
+}
+
+// LookupApproxContext checks the VAT number with VIES and compares the trader details against the ones on record.
+func (s *viesService) LookupApproxContext(ctx context.Context, req ApproxRequest) (*ApproxResult, error) {
+	if len(req.VATNumber) < 3 {
+		return nil, ErrInvalidVATNumberFormat
+	}
+
+	xmlRes, err := s.call(ctx, s.getApproxEnvelope(req))
+	if err != nil {
+		return nil, err
+	}
+
+	var rd struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Soap    struct {
+			XMLName xml.Name `xml:"Body"`
+			Soap    struct {
+				XMLName                xml.Name `xml:"checkVatApproxResponse"`
+				CountryCode            string   `xml:"countryCode"`
+				VATNumber              string   `xml:"vatNumber"`
+				RequestDate            string   `xml:"requestDate"`
+				Valid                  bool     `xml:"valid"`
+				TraderName             string   `xml:"traderName"`
+				TraderCompanyType      string   `xml:"traderCompanyType"`
+				TraderAddress          string   `xml:"traderAddress"`
+				TraderStreet           string   `xml:"traderStreet"`
+				TraderPostcode         string   `xml:"traderPostcode"`
+				TraderCity             string   `xml:"traderCity"`
+				TraderNameMatch        int      `xml:"traderNameMatch"`
+				TraderCompanyTypeMatch int      `xml:"traderCompanyTypeMatch"`
+				TraderStreetMatch      int      `xml:"traderStreetMatch"`
+				TraderPostcodeMatch    int      `xml:"traderPostcodeMatch"`
+				TraderCityMatch        int      `xml:"traderCityMatch"`
+				RequestIdentifier      string   `xml:"requestIdentifier"`
+			}
+		}
+	}
+	if err = xml.Unmarshal(xmlRes, &rd); err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+
+	r := rd.Soap.Soap
+	return &ApproxResult{
+		ValidationResult: ValidationResult{
+			CountryCode:        r.CountryCode,
+			VATNumber:          r.VATNumber,
+			Valid:              r.Valid,
+			Name:               cleanViesField(r.TraderName),
+			Address:            cleanViesField(r.TraderAddress),
+			RequestDate:        parseViesDate(r.RequestDate),
+			Service:            ServiceVIES,
+			Attempts:           1,
+			ConsultationNumber: r.RequestIdentifier,
+		},
+		TraderCompanyType: cleanViesField(r.TraderCompanyType),
+		TraderStreet:      cleanViesField(r.TraderStreet),
+		TraderPostcode:    cleanViesField(r.TraderPostcode),
+		TraderCity:        cleanViesField(r.TraderCity),
+		NameMatch:         MatchResult(r.TraderNameMatch),
+		CompanyTypeMatch:  MatchResult(r.TraderCompanyTypeMatch),
+		StreetMatch:       MatchResult(r.TraderStreetMatch),
+		PostcodeMatch:     MatchResult(r.TraderPostcodeMatch),
+		CityMatch:         MatchResult(r.TraderCityMatch),
+	}, nil
+}
+
+// getApproxEnvelope builds the VIES checkVatApprox envelope
+func (s *viesService) getApproxEnvelope(req ApproxRequest) string {
+	n := strings.ToUpper(req.VATNumber)
+
+	var b strings.Builder
+	b.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Header/>
+<soapenv:Body>
+  <checkVatApprox xmlns="urn:ec.europa.eu:taxud:vies:services:checkVat:types">
+`)
+	writeXMLElement(&b, "countryCode", n[0:2])
+	writeXMLElement(&b, "vatNumber", n[2:])
+	writeXMLElement(&b, "traderName", req.TraderName)
+	writeXMLElement(&b, "traderCompanyType", req.TraderCompanyType)
+	writeXMLElement(&b, "traderStreet", req.TraderStreet)
+	writeXMLElement(&b, "traderPostcode", req.TraderPostcode)
+	writeXMLElement(&b, "traderCity", req.TraderCity)
+	if r := strings.ToUpper(req.RequesterVATNumber); len(r) > 2 {
+		writeXMLElement(&b, "requesterCountryCode", r[0:2])
+		writeXMLElement(&b, "requesterVatNumber", r[2:])
 	}
+	b.WriteString(`  </checkVatApprox>
+</soapenv:Body>
+</soapenv:Envelope>`)
+	return b.String()
+}
 
-	if !r.Valid {
-		return ErrVATNumberNotFound
+// writeXMLElement writes an element with escaped text content to the envelope, skipping empty values
+func writeXMLElement(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
 	}
-	return nil
+	b.WriteString("\t<" + name + ">")
+	_ = xml.EscapeText(b, []byte(value))
+	b.WriteString("</" + name + ">\n")
 }
 
 // getEnvelope parses VIES lookup envelope template
@@ -104,23 +206,80 @@ func (s *viesService) getEnvelope(n string) string {
 	return e
 }
 
+// call posts the envelope to VIES and returns the response body, or an error if VIES reported one
+func (s *viesService) call(ctx context.Context, envelope string) ([]byte, error) {
+	res, err := s.lookup(ctx, envelope)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	xmlRes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, ErrServiceUnavailable{Err: err} // assume if we can't read the body then VIES gave us a bad response
+	}
+
+	var fault struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Body    struct {
+			Fault *struct {
+				Code   string `xml:"faultcode"`
+				String string `xml:"faultstring"`
+			} `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	if err = xml.Unmarshal(xmlRes, &fault); err != nil {
+		if res.StatusCode != http.StatusOK {
+			return nil, ErrServiceUnavailable{Err: fmt.Errorf("unexpected status code from VIES: %d", res.StatusCode)}
+		}
+		return nil, ErrServiceUnavailable{Err: err}
+	}
+	if f := fault.Body.Fault; f != nil {
+		return nil, &ViesFaultError{Code: parseViesFaultCode(f.String), Message: strings.TrimSpace(f.String)}
+	}
+
+	return xmlRes, nil
+}
+
 // lookup calls the VIES service to get info about the VAT number
-func (s *viesService) lookup(envelope string) (*http.Response, error) {
-	envelopeBuffer := bytes.NewBufferString(envelope)
-	client := http.Client{
-		Timeout: serviceTimeout,
+func (s *viesService) lookup(ctx context.Context, envelope string) (*http.Response, error) {
+	c := s.getClient()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.getViesURL(), bytes.NewBufferString(envelope))
+	if err != nil {
+		return nil, err
 	}
-	return client.Post(viesServiceURL, "text/xml;charset=UTF-8", envelopeBuffer)
+	req.Header.Set("Content-Type", "text/xml;charset=UTF-8")
+	return c.do(req)
+}
+
+func (s *viesService) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	return defaultClient
 }
 
 const viesServiceURL = "https://ec.europa.eu/taxation_customs/vies/services/checkVatService"
 
-// viesResponse holds the response data from the Vies call
-type viesResponse struct {
-	CountryCode string
-	VATNumber   string
-	RequestDate string
-	Valid       bool
-	Name        string
-	Address     string
+// parseViesDate parses the date format used by VIES, e.g. "2015-03-06+01:00".
+// A zero time is returned if the date can't be parsed.
+func parseViesDate(d string) time.Time {
+	for _, layout := range []string{"2006-01-02-07:00", "2006-01-02Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, d); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// cleanViesField trims a name or address returned by VIES. Member states that don't share
+// these details return "---" instead.
+func cleanViesField(f string) string {
+	f = strings.TrimSpace(f)
+	if f == "---" {
+		return ""
+	}
+	return f
 }
@@ -0,0 +1,190 @@
+package vat
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ViesStatusResult holds the availability of VIES and the member state back ends it checks VAT numbers against.
+type ViesStatusResult struct {
+	// Available tells whether VIES itself is available
+	Available bool
+	// MemberStates holds the availability per member state, keyed by the country code used in VAT numbers
+	MemberStates map[string]bool
+	CheckedAt    time.Time
+}
+
+// MemberStateAvailable returns whether VIES can currently check VAT numbers of the given member state.
+// Member states missing from the status are reported as available.
+func (r *ViesStatusResult) MemberStateAvailable(countryCode string) bool {
+	if !r.Available {
+		return false
+	}
+	available, ok := r.MemberStates[countryCode]
+	return !ok || available
+}
+
+// ViesStatus returns the availability of VIES and each member state back end.
+// The status is always retrieved from the VIES REST API, regardless of the VIES backend used for lookups.
+func ViesStatus() (*ViesStatusResult, error) {
+	return defaultClient.ViesStatus()
+}
+
+// ViesStatusContext is like ViesStatus, but the context is used for the call to VIES.
+func ViesStatusContext(ctx context.Context) (*ViesStatusResult, error) {
+	return defaultClient.ViesStatusContext(ctx)
+}
+
+// ViesStatus returns the availability of VIES and each member state back end.
+func (c *Client) ViesStatus() (*ViesStatusResult, error) {
+	return c.ViesStatusContext(context.Background())
+}
+
+// ViesStatusContext is like ViesStatus, but the context is used for the call to VIES.
+func (c *Client) ViesStatusContext(ctx context.Context) (*ViesStatusResult, error) {
+	var rd struct {
+		Vow struct {
+			Available bool `json:"available"`
+		} `json:"vow"`
+		Countries []struct {
+			CountryCode  string `json:"countryCode"`
+			Availability string `json:"availability"`
+		} `json:"countries"`
+	}
+	if err := (&viesRESTService{client: c}).call(ctx, "GET", "/check-status", nil, &rd); err != nil {
+		return nil, err
+	}
+
+	r := &ViesStatusResult{
+		Available:    rd.Vow.Available,
+		MemberStates: make(map[string]bool, len(rd.Countries)),
+		CheckedAt:    time.Now(),
+	}
+	for _, country := range rd.Countries {
+		r.MemberStates[country.CountryCode] = country.Availability == "Available"
+	}
+	return r, nil
+}
+
+// ViesStatusPoller periodically retrieves the VIES status in the background, so that lookups for member states
+// that are known to be unavailable can fail fast with ErrMemberStateUnavailable instead of waiting for VIES.
+// Pass it to a client with WithViesStatusPoller, or to the package-level functions with SetViesStatusPoller.
+type ViesStatusPoller struct {
+	client   *Client
+	interval time.Duration
+
+	mu     sync.RWMutex
+	status *ViesStatusResult
+	err    error
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// defaultViesStatusInterval is how often the VIES status is polled if no positive interval is given
+const defaultViesStatusInterval = time.Minute
+
+// NewViesStatusPoller returns a poller that retrieves the VIES status with the given client every interval.
+// If client is nil, the default client is used. If interval isn't positive, the status is polled every minute.
+// Call Start to start polling.
+func NewViesStatusPoller(client *Client, interval time.Duration) *ViesStatusPoller {
+	if client == nil {
+		client = defaultClient
+	}
+	if interval <= 0 {
+		interval = defaultViesStatusInterval
+	}
+	return &ViesStatusPoller{
+		client:   client,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start retrieves the status right away and then keeps polling in the background until Stop is called
+// or the context is done. Calling Start on a poller that was already started has no effect.
+func (p *ViesStatusPoller) Start(ctx context.Context) {
+	done := make(chan struct{})
+	p.mu.Lock()
+	if p.done != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.done = done
+	p.mu.Unlock()
+
+	_ = p.Refresh(ctx)
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				_ = p.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for the background goroutine to exit.
+func (p *ViesStatusPoller) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+
+	p.mu.RLock()
+	done := p.done
+	p.mu.RUnlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Refresh retrieves the status now. A failed refresh keeps the last retrieved status until it goes stale.
+func (p *ViesStatusPoller) Refresh(ctx context.Context) error {
+	status, err := p.client.ViesStatusContext(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+	if err == nil {
+		p.status = status
+	}
+	return err
+}
+
+// Status returns the last retrieved status, or nil if none was retrieved yet, along with the error of the last refresh.
+func (p *ViesStatusPoller) Status() (*ViesStatusResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status, p.err
+}
+
+// checkMemberState returns ErrMemberStateUnavailable if the last retrieved status, provided it is not stale,
+// reports the member state as unavailable.
+func (p *ViesStatusPoller) checkMemberState(countryCode string) error {
+	status, _ := p.Status()
+	if status == nil || time.Since(status.CheckedAt) > 2*p.interval {
+		return nil
+	}
+	if !status.MemberStateAvailable(countryCode) {
+		return ErrMemberStateUnavailable{Country: countryCode}
+	}
+	return nil
+}
+
+// SetViesStatusPoller sets the poller the package-level functions use to fail fast for unavailable member states.
+// Pass nil to stop using one. It is safe to call while lookups are in progress.
+func SetViesStatusPoller(p *ViesStatusPoller) {
+	defaultClient.viesStatusPoller.Store(p)
+}
@@ -0,0 +1,113 @@
+package vat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const viesStatusResponse = `{
+	"vow": {"available": true},
+	"countries": [
+		{"countryCode": "BE", "availability": "Available"},
+		{"countryCode": "DE", "availability": "Unavailable"}
+	]
+}`
+
+func TestViesStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/check-status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, viesStatusResponse)
+	}))
+	defer server.Close()
+
+	status, err := NewClient(WithViesRESTURL(server.URL)).ViesStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available || !status.MemberStateAvailable("BE") || status.MemberStateAvailable("DE") {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if !status.MemberStateAvailable("NL") {
+		t.Error("Expected member state missing from the status to be reported as available")
+	}
+}
+
+func TestViesStatusPoller(t *testing.T) {
+	var viesCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/check-status" {
+			_, _ = io.WriteString(w, viesStatusResponse)
+			return
+		}
+		atomic.AddInt32(&viesCalls, 1)
+		_, _ = io.WriteString(w, viesValidResponse)
+	}))
+	defer server.Close()
+
+	poller := NewViesStatusPoller(NewClient(WithViesRESTURL(server.URL)), time.Minute)
+	poller.Start(context.Background())
+	defer poller.Stop()
+
+	c := NewClient(WithViesURL(server.URL), WithViesStatusPoller(poller))
+
+	err := c.ValidateExists("DE123456789")
+	if !errors.Is(err, ErrMemberStateUnavailable{Country: "DE"}) || !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected <%v>, got <%v>", ErrMemberStateUnavailable{Country: "DE"}, err)
+	}
+	if atomic.LoadInt32(&viesCalls) != 0 {
+		t.Error("Expected VIES not to be called for an unavailable member state")
+	}
+
+	if err = c.ValidateExists("BE0472429986"); err != nil {
+		t.Errorf("Expected <nil> for BE0472429986, got <%v>", err)
+	}
+	if atomic.LoadInt32(&viesCalls) != 1 {
+		t.Error("Expected VIES to be called for an available member state")
+	}
+}
+
+func TestViesStatusPollerStart(t *testing.T) {
+	var statusCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&statusCalls, 1)
+		_, _ = io.WriteString(w, viesStatusResponse)
+	}))
+	defer server.Close()
+
+	// a non-positive interval falls back to the default instead of making the ticker panic
+	poller := NewViesStatusPoller(NewClient(WithViesRESTURL(server.URL)), 0)
+	if poller.interval != defaultViesStatusInterval {
+		t.Errorf("Expected interval %s, got %s", defaultViesStatusInterval, poller.interval)
+	}
+
+	// starting twice doesn't start a second goroutine
+	poller.Start(context.Background())
+	poller.Start(context.Background())
+	poller.Stop()
+	if n := atomic.LoadInt32(&statusCalls); n != 1 {
+		t.Errorf("Expected 1 status call, got %d", n)
+	}
+
+	// the poller of the package-level functions can be changed while lookups are in progress
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, _ = defaultClient.lookupServiceFor("BE0472429986", ValidatorOpts{})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		SetViesStatusPoller(poller)
+		SetViesStatusPoller(nil)
+	}
+	<-done
+}
@@ -0,0 +1,90 @@
+package vat
+
+import (
+	"context"
+	"strings"
+)
+
+// XIRouting sets which service Northern Ireland VAT numbers starting with XI are checked with. Traders in Northern
+// Ireland that trade goods with the EU are registered in VIES with their XI number, while HMRC knows the same
+// registration by the GB number with the same digits, see XIToGB.
+type XIRouting int
+
+const (
+	// XIRoutingVIES checks XI numbers with VIES.
+	XIRoutingVIES XIRouting = iota
+	// XIRoutingHMRC checks the GB counterpart of XI numbers with the UK VAT API.
+	XIRoutingHMRC
+	// XIRoutingBoth checks XI numbers with VIES and their GB counterpart with the UK VAT API. The result of the
+	// UK VAT API is reported in ValidationResult.Counterpart, and the number is only valid if both registrations are.
+	XIRoutingBoth
+)
+
+// XIToGB returns the GB VAT number of the same registration as a Northern Ireland XI number.
+func XIToGB(vatNumber string) (string, error) {
+	vatNumber = normalizeVATNumber(vatNumber)
+	if !strings.HasPrefix(vatNumber, "XI") {
+		return "", ErrInvalidCountryCode
+	}
+	if err := ValidateFormat(vatNumber); err != nil {
+		return "", err
+	}
+	return "GB" + vatNumber[2:], nil
+}
+
+// xiService checks Northern Ireland numbers with the UK VAT API and, if vies is set, with VIES as well
+type xiService struct {
+	vies LookupServiceInterface
+	uk   LookupServiceInterface
+}
+
+// Validate returns whether the given VAT number is valid or not
+func (s *xiService) Validate(vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.Lookup(vatNumber, opts))
+}
+
+// ValidateContext is like Validate, but the context is used for the calls to the lookup services.
+func (s *xiService) ValidateContext(ctx context.Context, vatNumber string, opts ValidatorOpts) error {
+	return validateResult(s.LookupContext(ctx, vatNumber, opts))
+}
+
+// Lookup returns the details the lookup services hold for the given VAT number.
+func (s *xiService) Lookup(vatNumber string, opts ValidatorOpts) (*ValidationResult, error) {
+	return s.lookup(vatNumber, func(service LookupServiceInterface, n string) (*ValidationResult, error) {
+		return service.Lookup(n, opts)
+	})
+}
+
+// LookupContext is like Lookup, but the context is used for the calls to the lookup services.
+func (s *xiService) LookupContext(
+	ctx context.Context,
+	vatNumber string,
+	opts ValidatorOpts,
+) (*ValidationResult, error) {
+	return s.lookup(vatNumber, func(service LookupServiceInterface, n string) (*ValidationResult, error) {
+		return service.LookupContext(ctx, n, opts)
+	})
+}
+
+func (s *xiService) lookup(
+	vatNumber string,
+	lookup func(service LookupServiceInterface, n string) (*ValidationResult, error),
+) (*ValidationResult, error) {
+	gb := "GB" + vatNumber[2:]
+	if s.vies == nil {
+		return lookup(s.uk, gb)
+	}
+
+	r, err := lookup(s.vies, vatNumber)
+	if err != nil {
+		return nil, err
+	}
+	counterpart, err := lookup(s.uk, gb)
+	if err != nil {
+		return nil, err
+	}
+
+	r = copyResult(r)
+	r.Counterpart = counterpart
+	return r, nil
+}
@@ -0,0 +1,80 @@
+package vat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestXIToGB(t *testing.T) {
+	tests := []struct {
+		vatNumber     string
+		expected      string
+		expectedError error
+	}{
+		{"XI999999973", "GB999999973", nil},
+		{"xi 999 9999 73 001", "GB999999973001", nil},
+		{"XI999999974", "", ErrInvalidVATNumberChecksum},
+		{"GB999999973", "", ErrInvalidCountryCode},
+		{"XI", "", ErrInvalidVATNumberFormat},
+	}
+
+	for _, test := range tests {
+		gb, err := XIToGB(test.vatNumber)
+		if gb != test.expected || !errors.Is(err, test.expectedError) {
+			t.Errorf("Expected %q <%v> for %v, got %q <%v>", test.expected, test.expectedError, test.vatNumber, gb, err)
+		}
+	}
+}
+
+func TestXIRouting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockViesService := NewMockLookupServiceInterface(ctrl)
+	mockUKVATService := NewMockLookupServiceInterface(ctrl)
+	c := NewClient(WithViesLookupService(mockViesService), WithUKLookupService(mockUKVATService))
+
+	xiResult := &ValidationResult{CountryCode: "XI", VATNumber: "999999973", Valid: true, Service: ServiceVIES}
+	gbResult := &ValidationResult{CountryCode: "GB", VATNumber: "999999973", Valid: true, Service: ServiceHMRC}
+	gbNotFound := &ValidationResult{CountryCode: "GB", VATNumber: "999999973", Service: ServiceHMRC}
+
+	// VIES is used by default
+	opts := ValidatorOpts{}
+	mockViesService.EXPECT().Lookup("XI999999973", opts).Return(xiResult, nil)
+	if r, err := c.Lookup("XI999999973", opts); err != nil || r != xiResult {
+		t.Errorf("Expected the VIES result, got %+v <%v>", r, err)
+	}
+
+	// the GB counterpart is looked up with HMRC
+	opts = ValidatorOpts{XIRouting: XIRoutingHMRC}
+	mockUKVATService.EXPECT().Lookup("GB999999973", opts).Return(gbResult, nil)
+	if r, err := c.Lookup("XI999999973", opts); err != nil || r != gbResult {
+		t.Errorf("Expected the HMRC result, got %+v <%v>", r, err)
+	}
+
+	// both registrations are checked and reported
+	opts = ValidatorOpts{XIRouting: XIRoutingBoth}
+	mockViesService.EXPECT().Lookup("XI999999973", opts).Return(xiResult, nil)
+	mockUKVATService.EXPECT().Lookup("GB999999973", opts).Return(gbResult, nil)
+	r, err := c.Lookup("XI999999973", opts)
+	if err != nil || !r.Valid || r.Service != ServiceVIES || r.Counterpart != gbResult {
+		t.Errorf("Expected the VIES result with the HMRC counterpart, got %+v <%v>", r, err)
+	}
+	if xiResult.Counterpart != nil {
+		t.Error("Expected the result of the lookup service not to be modified")
+	}
+
+	// the number is only valid if both registrations are
+	mockViesService.EXPECT().Lookup("XI999999973", opts).Return(xiResult, nil)
+	mockUKVATService.EXPECT().Lookup("GB999999973", opts).Return(gbNotFound, nil)
+	if err = c.Validate("XI999999973", opts); !errors.Is(err, ErrVATNumberNotFound) {
+		t.Errorf("Expected <%v>, got <%v>", ErrVATNumberNotFound, err)
+	}
+
+	mockViesService.EXPECT().Lookup("XI999999973", opts).Return(nil, ErrServiceUnavailable{})
+	if err = c.Validate("XI999999973", opts); !errors.Is(err, ErrServiceUnavailable{}) {
+		t.Errorf("Expected <%v>, got <%v>", ErrServiceUnavailable{}, err)
+	}
+}